@@ -0,0 +1,344 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"sort"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// the features, which can be switched on and off with the feature gates
+const (
+	// serve the defaulting and validating webhooks of the global objects
+	FeatureWebhooks = "Webhooks"
+
+	// serve the conversion webhook of the global objects, which the CustomResourceDefinitions
+	// require, since v1 is the storage version, it may only be switched off, if the
+	// CustomResourceDefinitions point to the webhook of another instance, e.g. with "make run"
+	FeatureConversionWebhook = "ConversionWebhook"
+
+	// restart the workloads of the global objects with spec.rollout
+	FeatureWorkloadRollout = "WorkloadRollout"
+)
+
+// the features and whether they are enabled by default
+var DefaultFeatureGates = map[string]bool{
+	FeatureWebhooks:          true,
+	FeatureConversionWebhook: true,
+	FeatureWorkloadRollout:   true,
+}
+
+//+kubebuilder:object:root=true
+
+// ControllerManagerConfig is the configuration file of the manager, every field
+// can also be set with the flag of the same name, which overrides the file
+type ControllerManagerConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	Metrics        MetricsConfig        `json:"metrics,omitempty"`
+	Health         HealthConfig         `json:"health,omitempty"`
+	LeaderElection LeaderElectionConfig `json:"leaderElection,omitempty"`
+	Webhook        WebhookConfig        `json:"webhook,omitempty"`
+
+	// the namespaces, which are never replicated into, unless a global object opts in
+	// with the annotation globals.jnnkrdb.de/allow-protected-namespaces, the namespace
+	// of the manager is always protected
+	ProtectedNamespaces []string `json:"protectedNamespaces,omitempty"`
+
+	// the namespaces, whose global objects are reconciled, all namespaces if empty
+	WatchNamespaces []string `json:"watchNamespaces,omitempty"`
+
+	// the only namespaces, which are replicated into, all namespaces if empty
+	TargetNamespaces []string `json:"targetNamespaces,omitempty"`
+
+	Orphans    OrphansConfig    `json:"orphans,omitempty"`
+	Sharding   ShardingConfig   `json:"sharding,omitempty"`
+	Controller ControllerConfig `json:"controller,omitempty"`
+	Tracing    TracingConfig    `json:"tracing,omitempty"`
+	Audit      AuditConfig      `json:"audit,omitempty"`
+
+	// the features, which are switched on or off, see [DefaultFeatureGates]
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+}
+
+// the endpoint of the metrics
+type MetricsConfig struct {
+	// the address the metric endpoint binds to
+	BindAddress string `json:"bindAddress,omitempty"`
+}
+
+// the endpoints of the probes
+type HealthConfig struct {
+	// the address the probe endpoint binds to
+	HealthProbeBindAddress string `json:"healthProbeBindAddress,omitempty"`
+}
+
+// the leader election of the replicas
+type LeaderElectionConfig struct {
+	// ensure, that there is only one active manager
+	LeaderElect bool `json:"leaderElect,omitempty"`
+
+	// the name of the lease, which is held by the leader
+	ResourceName string `json:"resourceName,omitempty"`
+}
+
+// the server of the webhooks
+type WebhookConfig struct {
+	// the port the webhook server listens on
+	Port int `json:"port,omitempty"`
+
+	// the directory, which contains the certificate and the key of the webhook server
+	CertDir string `json:"certDir,omitempty"`
+}
+
+// the search for orphans
+type OrphansConfig struct {
+	// what happens with the orphans, one of delete, report or ignore
+	Policy string `json:"policy,omitempty"`
+
+	// the interval, in which the orphans are searched, 0 only searches at the start
+	Interval metav1.Duration `json:"interval,omitempty"`
+}
+
+// the sharding of the global objects across the replicas
+type ShardingConfig struct {
+	// the number of shards, 0 disables the sharding
+	Shards int `json:"shards,omitempty"`
+
+	// the duration, after which the shards of a replica, which did not renew its leases, are claimed
+	LeaseDuration metav1.Duration `json:"leaseDuration,omitempty"`
+
+	// the interval, in which a replica renews its leases and rebalances the shards
+	RenewInterval metav1.Duration `json:"renewInterval,omitempty"`
+}
+
+// the controllers of the global objects
+type ControllerConfig struct {
+	// the number of global objects of a kind, which are reconciled at the same time
+	MaxConcurrentReconciles int `json:"maxConcurrentReconciles,omitempty"`
+
+	// the interval, in which the global objects are reconciled again without an event
+	ResyncPeriod metav1.Duration `json:"resyncPeriod,omitempty"`
+
+	RateLimiter RateLimiterConfig `json:"rateLimiter,omitempty"`
+
+	// the secret in the namespace of the manager, whose key "key" keys the content
+	// hashes of the replicated objects, the secret is created, if it does not exist
+	ContentHashKeySecret string `json:"contentHashKeySecret,omitempty"`
+}
+
+// the rate limiter of the controllers
+type RateLimiterConfig struct {
+	// the delay of the first retry of a failed reconciliation
+	BaseDelay metav1.Duration `json:"baseDelay,omitempty"`
+
+	// the maximum delay of the retries of a failed reconciliation
+	MaxDelay metav1.Duration `json:"maxDelay,omitempty"`
+
+	// the number of reconciliations per second and kind
+	QPS float64 `json:"qps,omitempty"`
+
+	// the number of reconciliations, which may exceed the qps in a burst
+	Burst int `json:"burst,omitempty"`
+}
+
+// the tracing of the reconciliations
+type TracingConfig struct {
+	// where the spans are exported to, one of none, stdout or otlp
+	Exporter string `json:"exporter,omitempty"`
+
+	// the address of the otlp collector, which receives the spans over grpc
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// connect to the otlp collector without tls
+	Insecure bool `json:"insecure,omitempty"`
+
+	// the fraction of the reconciliations, which are traced, between 0 and 1
+	SampleRatio float64 `json:"sampleRatio,omitempty"`
+}
+
+// the audit of the changes to the cluster
+type AuditConfig struct {
+	// the file, which the records are appended to, "-" writes them to stdout, empty disables the file
+	Path string `json:"path,omitempty"`
+
+	// the configmap in the namespace of the manager, which keeps the latest records, empty disables the configmap
+	ConfigMap string `json:"configMap,omitempty"`
+
+	// the number of records, which are kept in the configmap
+	ConfigMapSize int `json:"configMapSize,omitempty"`
+
+	// the interval, in which the buffered records are written to the configmap
+	ConfigMapFlushInterval metav1.Duration `json:"configMapFlushInterval,omitempty"`
+
+	// the secret in the namespace of the manager, whose key "key" signs the records,
+	// required, if the audit is enabled
+	KeySecret string `json:"keySecret,omitempty"`
+}
+
+// get the configuration, which is used without a configuration file and flags
+func Defaults() *ControllerManagerConfig {
+	return &ControllerManagerConfig{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: GroupVersion.String(),
+			Kind:       Kind,
+		},
+		Metrics: MetricsConfig{BindAddress: ":8080"},
+		Health:  HealthConfig{HealthProbeBindAddress: ":8081"},
+		LeaderElection: LeaderElectionConfig{
+			ResourceName: "80de5eb2.jnnkrdb.de",
+		},
+		Webhook: WebhookConfig{Port: 9443},
+		Orphans: OrphansConfig{
+			Policy:   "report",
+			Interval: metav1.Duration{Duration: time.Hour},
+		},
+		Sharding: ShardingConfig{
+			LeaseDuration: metav1.Duration{Duration: 15 * time.Second},
+			RenewInterval: metav1.Duration{Duration: 5 * time.Second},
+		},
+		Controller: ControllerConfig{
+			MaxConcurrentReconciles: 1,
+			ResyncPeriod:            metav1.Duration{Duration: 3 * time.Minute},
+			RateLimiter: RateLimiterConfig{
+				BaseDelay: metav1.Duration{Duration: 5 * time.Millisecond},
+				MaxDelay:  metav1.Duration{Duration: 1000 * time.Second},
+				QPS:       10,
+				Burst:     100,
+			},
+			ContentHashKeySecret: "confrdb-content-hash",
+		},
+		Tracing: TracingConfig{
+			Exporter:    "none",
+			Endpoint:    "localhost:4317",
+			Insecure:    true,
+			SampleRatio: 1,
+		},
+		Audit: AuditConfig{
+			ConfigMapSize:          500,
+			ConfigMapFlushInterval: metav1.Duration{Duration: 5 * time.Second},
+		},
+	}
+}
+
+// check, whether a feature is enabled
+func (cfg *ControllerManagerConfig) Enabled(feature string) bool {
+	if enabled, ok := cfg.FeatureGates[feature]; ok {
+		return enabled
+	}
+	return DefaultFeatureGates[feature]
+}
+
+// validate the configuration, all invalid fields are returned at once
+func (cfg *ControllerManagerConfig) Validate() field.ErrorList {
+
+	var errs field.ErrorList
+
+	if cfg.Webhook.Port < 1 || cfg.Webhook.Port > 65535 {
+		errs = append(errs, field.Invalid(field.NewPath("webhook", "port"), cfg.Webhook.Port, "must be a port between 1 and 65535"))
+	}
+	if cfg.LeaderElection.LeaderElect && cfg.LeaderElection.ResourceName == "" {
+		errs = append(errs, field.Required(field.NewPath("leaderElection", "resourceName"), "the leader election requires the name of the lease"))
+	}
+
+	switch cfg.Orphans.Policy {
+	case "delete", "report", "ignore":
+	default:
+		errs = append(errs, field.NotSupported(field.NewPath("orphans", "policy"), cfg.Orphans.Policy, []string{"delete", "report", "ignore"}))
+	}
+	if cfg.Orphans.Interval.Duration < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("orphans", "interval"), cfg.Orphans.Interval.Duration.String(), "must not be negative"))
+	}
+
+	var sharding = field.NewPath("sharding")
+	if cfg.Sharding.Shards < 0 {
+		errs = append(errs, field.Invalid(sharding.Child("shards"), cfg.Sharding.Shards, "must not be negative"))
+	}
+	if cfg.Sharding.Shards > 0 {
+		if cfg.LeaderElection.LeaderElect {
+			errs = append(errs, field.Forbidden(field.NewPath("leaderElection", "leaderElect"), "the sharding replaces the leader election"))
+		}
+		if cfg.Sharding.RenewInterval.Duration <= 0 || cfg.Sharding.RenewInterval.Duration >= cfg.Sharding.LeaseDuration.Duration {
+			errs = append(errs, field.Invalid(sharding.Child("renewInterval"), cfg.Sharding.RenewInterval.Duration.String(), "must be positive and shorter than the lease duration"))
+		}
+	}
+
+	var controller = field.NewPath("controller")
+	if cfg.Controller.MaxConcurrentReconciles < 1 {
+		errs = append(errs, field.Invalid(controller.Child("maxConcurrentReconciles"), cfg.Controller.MaxConcurrentReconciles, "must be at least 1"))
+	}
+	if cfg.Controller.ResyncPeriod.Duration < 0 {
+		errs = append(errs, field.Invalid(controller.Child("resyncPeriod"), cfg.Controller.ResyncPeriod.Duration.String(), "must not be negative"))
+	}
+	var rl = controller.Child("rateLimiter")
+	if cfg.Controller.RateLimiter.BaseDelay.Duration <= 0 || cfg.Controller.RateLimiter.BaseDelay.Duration > cfg.Controller.RateLimiter.MaxDelay.Duration {
+		errs = append(errs, field.Invalid(rl.Child("baseDelay"), cfg.Controller.RateLimiter.BaseDelay.Duration.String(), "must be positive and not longer than the maximum delay"))
+	}
+	if cfg.Controller.RateLimiter.QPS <= 0 {
+		errs = append(errs, field.Invalid(rl.Child("qps"), cfg.Controller.RateLimiter.QPS, "must be positive"))
+	}
+	if cfg.Controller.RateLimiter.Burst < 1 {
+		errs = append(errs, field.Invalid(rl.Child("burst"), cfg.Controller.RateLimiter.Burst, "must be at least 1"))
+	}
+	if cfg.Controller.ContentHashKeySecret == "" {
+		errs = append(errs, field.Required(controller.Child("contentHashKeySecret"), "the content hashes require the secret of their key"))
+	}
+
+	var tracing = field.NewPath("tracing")
+	switch cfg.Tracing.Exporter {
+	case "none", "stdout", "otlp":
+	default:
+		errs = append(errs, field.NotSupported(tracing.Child("exporter"), cfg.Tracing.Exporter, []string{"none", "stdout", "otlp"}))
+	}
+	if cfg.Tracing.Exporter == "otlp" && cfg.Tracing.Endpoint == "" {
+		errs = append(errs, field.Required(tracing.Child("endpoint"), "the otlp exporter requires the address of the collector"))
+	}
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		errs = append(errs, field.Invalid(tracing.Child("sampleRatio"), cfg.Tracing.SampleRatio, "must be between 0 and 1"))
+	}
+
+	var audit = field.NewPath("audit")
+	if cfg.Audit.ConfigMap != "" && cfg.Audit.ConfigMapSize < 1 {
+		errs = append(errs, field.Invalid(audit.Child("configMapSize"), cfg.Audit.ConfigMapSize, "must be at least 1"))
+	}
+	if cfg.Audit.ConfigMap != "" && cfg.Audit.ConfigMapFlushInterval.Duration <= 0 {
+		errs = append(errs, field.Invalid(audit.Child("configMapFlushInterval"), cfg.Audit.ConfigMapFlushInterval.Duration.String(), "must be positive"))
+	}
+	if (cfg.Audit.Path != "" || cfg.Audit.ConfigMap != "") && cfg.Audit.KeySecret == "" {
+		errs = append(errs, field.Required(audit.Child("keySecret"), "the audit requires the secret of the key, which signs the records"))
+	}
+
+	for feature := range cfg.FeatureGates {
+		if _, ok := DefaultFeatureGates[feature]; !ok {
+			errs = append(errs, field.NotSupported(field.NewPath("featureGates").Key(feature), feature, KnownFeatures()))
+		}
+	}
+	return errs
+}
+
+// get the names of all features in alphabetical order
+func KnownFeatures() []string {
+	var features = make([]string, 0, len(DefaultFeatureGates))
+	for feature := range DefaultFeatureGates {
+		features = append(features, feature)
+	}
+	sort.Strings(features)
+	return features
+}
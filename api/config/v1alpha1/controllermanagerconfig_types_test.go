@@ -0,0 +1,119 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+
+	// the configuration file, which is shipped with the manager, is valid
+	var shipped = Defaults()
+	if err := Load(filepath.Join("..", "..", "..", "config", "manager", "controller_manager_config.yaml"), shipped); err != nil {
+		t.Fatal(err)
+	}
+	if errs := shipped.Validate(); len(errs) > 0 {
+		t.Errorf("the shipped configuration is invalid: %v", errs)
+	}
+
+	var dir = t.TempDir()
+	for name, tc := range map[string]struct {
+		content string
+		wantErr string
+		check   func(*ControllerManagerConfig) bool
+	}{
+		"partial": {
+			content: "apiVersion: config.globals.jnnkrdb.de/v1alpha1\nkind: ControllerManagerConfig\nsharding:\n  shards: 4\n",
+			check: func(cfg *ControllerManagerConfig) bool {
+				// the missing fields keep the defaults
+				return cfg.Sharding.Shards == 4 && cfg.Sharding.LeaseDuration.Duration == 15*time.Second && cfg.Webhook.Port == 9443
+			},
+		},
+		"unknown field": {
+			content: "apiVersion: config.globals.jnnkrdb.de/v1alpha1\nkind: ControllerManagerConfig\nshard: 4\n",
+			wantErr: "unknown field",
+		},
+		"other kind": {
+			content: "apiVersion: controller-runtime.sigs.k8s.io/v1alpha1\nkind: ControllerManagerConfig\n",
+			wantErr: "expected config.globals.jnnkrdb.de/v1alpha1",
+		},
+	} {
+		var path = filepath.Join(dir, name+".yaml")
+		if err := os.WriteFile(path, []byte(tc.content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		var cfg = Defaults()
+		var err = Load(path, cfg)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("%s: expected an error containing %q, got %v", name, tc.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		} else if !tc.check(cfg) {
+			t.Errorf("%s: unexpected configuration %+v", name, cfg)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if errs := Defaults().Validate(); len(errs) > 0 {
+		t.Errorf("the defaults are invalid: %v", errs)
+	}
+
+	var cfg = Defaults()
+	cfg.LeaderElection.LeaderElect = true
+	cfg.Sharding.Shards = 4
+	cfg.Sharding.RenewInterval = cfg.Sharding.LeaseDuration
+	cfg.Controller.MaxConcurrentReconciles = 0
+	cfg.Controller.ContentHashKeySecret = ""
+	cfg.Orphans.Policy = "keep"
+	cfg.Tracing.SampleRatio = 2
+	cfg.Audit.ConfigMap, cfg.Audit.ConfigMapSize, cfg.Audit.ConfigMapFlushInterval.Duration = "confrdb-audit", 0, 0
+	cfg.FeatureGates = map[string]bool{"Unknown": true, FeatureWebhooks: false}
+
+	var fields []string
+	for _, err := range cfg.Validate() {
+		fields = append(fields, err.Field)
+	}
+	var want = []string{
+		"orphans.policy",
+		"leaderElection.leaderElect",
+		"sharding.renewInterval",
+		"controller.maxConcurrentReconciles",
+		"controller.contentHashKeySecret",
+		"tracing.sampleRatio",
+		"audit.configMapSize",
+		"audit.configMapFlushInterval",
+		"audit.keySecret",
+		"featureGates[Unknown]",
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("expected the invalid fields %v, got %v", want, fields)
+	}
+	if cfg.Enabled(FeatureWebhooks) || !cfg.Enabled(FeatureWorkloadRollout) {
+		t.Error("expected the feature gates to override the defaults")
+	}
+}
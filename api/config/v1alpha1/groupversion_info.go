@@ -0,0 +1,55 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package v1alpha1 contains the configuration file of the manager
+// +kubebuilder:object:generate=true
+package v1alpha1
+
+import (
+	"fmt"
+	"os"
+
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	// GroupVersion is group version of the configuration file
+	GroupVersion = schema.GroupVersion{Group: "config.globals.jnnkrdb.de", Version: "v1alpha1"}
+)
+
+// the kind of the configuration file
+const Kind = "ControllerManagerConfig"
+
+// read the configuration file at the path into the configuration
+//
+// the fields, which are missing in the file, keep their current values, unknown
+// fields and other versions or kinds are rejected
+func Load(path string, cfg *ControllerManagerConfig) error {
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err = yaml.UnmarshalStrict(data, cfg); err != nil {
+		return fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+	if cfg.APIVersion != GroupVersion.String() || cfg.Kind != Kind {
+		return fmt.Errorf("invalid configuration file %s: expected %s %s, got %s %s",
+			path, GroupVersion, Kind, cfg.APIVersion, cfg.Kind)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+// v1 is the hub of the conversions, the other versions convert from and to v1
+
+// Hub marks this type as a conversion hub.
+func (*GlobalConfig) Hub() {}
+
+// Hub marks this type as a conversion hub.
+func (*GlobalSecret) Hub() {}
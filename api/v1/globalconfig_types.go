@@ -0,0 +1,127 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GlobalConfigSpec defines the desired state of GlobalConfig
+type GlobalConfigSpec struct {
+
+	// the namespaces, into which the data is replicated
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Namespaces NamespaceSelector `json:"namespaces"`
+
+	// the data of the replicated configmaps
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Data map[string]string `json:"data,omitempty"`
+
+	// restart the Deployments, StatefulSets and DaemonSets, which consume the
+	// replicated configmap, whenever the data changes
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Rollout bool `json:"rollout,omitempty"`
+
+	// stop the replication, while suspended, the existing configmaps are neither
+	// created, updated nor removed, but the drift is still reported in the status
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Suspend bool `json:"suspend,omitempty"`
+
+	// only calculate the changes to the configmaps and report them in status.plan,
+	// without creating, updating or removing any configmaps
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// update the outdated configmaps in waves and batches, instead of
+	// updating all namespaces at once
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	RolloutStrategy *RolloutStrategy `json:"rolloutStrategy,omitempty"`
+
+	// the number of former revisions of the data, which are kept for a rollback,
+	// defaults to 10, 0 disables the history
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// the interval, in which the configmaps are checked again without an event, overrides
+	// the interval of the operator, 0 disables the periodic check
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	ResyncInterval *metav1.Duration `json:"resyncInterval,omitempty"`
+}
+
+// GlobalConfigStatus defines the observed state of GlobalConfig
+type GlobalConfigStatus struct {
+
+	// the state of the replicated configmaps in the matching namespaces
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Targets []TargetStatus `json:"targets,omitempty"`
+
+	// the number of the revision, which contains the current data
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	CurrentRevision int64 `json:"currentRevision,omitempty"`
+
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+
+	// the changes, which would be applied, if the dry run was disabled
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Plan *ReplicationPlan `json:"plan,omitempty"`
+
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+}
+
+// GlobalConfig is the Schema for the globalconfigs API
+// +kubebuilder:subresource:status
+// +kubebuilder:object:root=true
+// +kubebuilder:storageversion
+// +kubebuilder:resource:path=globalconfigs,shortName=gc;gcs
+// +kubebuilder:printcolumn:name="Synced",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="Revision",type="integer",JSONPath=".status.currentRevision"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type GlobalConfig struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GlobalConfigSpec   `json:"spec,omitempty"`
+	Status GlobalConfigStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GlobalConfigList contains a list of GlobalConfig
+type GlobalConfigList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GlobalConfig `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GlobalConfig{}, &GlobalConfigList{})
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GlobalSecretSpec defines the desired state of GlobalSecret
+type GlobalSecretSpec struct {
+
+	// the namespaces, into which the data is replicated
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Namespaces NamespaceSelector `json:"namespaces"`
+
+	// the type of the replicated secrets
+	// +kubebuilder:default=Opaque
+	// +kubebuilder:validation:Enum={"Opaque","kubernetes.io/service-account-token","kubernetes.io/dockercfg","kubernetes.io/dockerconfigjson","kubernetes.io/basic-auth","kubernetes.io/ssh-auth","kubernetes.io/tls","bootstrap.kubernetes.io/token"}
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Type corev1.SecretType `json:"type,omitempty"`
+
+	// the base64 encoded data of the replicated secrets
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Data SecretData `json:"data,omitempty"`
+
+	// restart the Deployments, StatefulSets and DaemonSets, which consume the
+	// replicated secret, whenever the data changes
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Rollout bool `json:"rollout,omitempty"`
+
+	// stop the replication, while suspended, the existing secrets are neither
+	// created, updated nor removed, but the drift is still reported in the status
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Suspend bool `json:"suspend,omitempty"`
+
+	// only calculate the changes to the secrets and report them in status.plan,
+	// without creating, updating or removing any secrets
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// update the outdated secrets in waves and batches, instead of
+	// updating all namespaces at once
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	RolloutStrategy *RolloutStrategy `json:"rolloutStrategy,omitempty"`
+
+	// the number of former revisions of the data, which are kept for a rollback,
+	// defaults to 10, 0 disables the history
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// the interval, in which the secrets are checked again without an event, overrides
+	// the interval of the operator, 0 disables the periodic check
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	ResyncInterval *metav1.Duration `json:"resyncInterval,omitempty"`
+}
+
+// GlobalSecretStatus defines the observed state of GlobalSecret
+type GlobalSecretStatus struct {
+
+	// the state of the replicated secrets in the matching namespaces
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Targets []TargetStatus `json:"targets,omitempty"`
+
+	// the number of the revision, which contains the current data
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	CurrentRevision int64 `json:"currentRevision,omitempty"`
+
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+
+	// the changes, which would be applied, if the dry run was disabled
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Plan *ReplicationPlan `json:"plan,omitempty"`
+
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
+}
+
+// GlobalSecret is the Schema for the globalsecrets API
+// +kubebuilder:subresource:status
+// +kubebuilder:object:root=true
+// +kubebuilder:storageversion
+// +kubebuilder:resource:path=globalsecrets,shortName=gs;gss
+// +kubebuilder:printcolumn:name="Type",type="string",JSONPath=".spec.type"
+// +kubebuilder:printcolumn:name="Synced",type="string",JSONPath=".status.conditions[?(@.type=='Synced')].status"
+// +kubebuilder:printcolumn:name="Revision",type="integer",JSONPath=".status.currentRevision"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+type GlobalSecret struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GlobalSecretSpec   `json:"spec,omitempty"`
+	Status GlobalSecretStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GlobalSecretList contains a list of GlobalSecret
+type GlobalSecretList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GlobalSecret `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GlobalSecret{}, &GlobalSecretList{})
+}
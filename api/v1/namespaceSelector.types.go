@@ -0,0 +1,43 @@
+package v1
+
+// the way the patterns of a NamespaceSelector are compared with the names of the namespaces
+// +kubebuilder:validation:Enum=Regex;AnchoredRegex;Glob;Exact
+type MatchMode string
+
+const (
+	// the patterns are regexpressions, which match substrings of the names,
+	// e.g. "prod" matches "prod", "preprod" and "product-x"
+	MatchModeRegex MatchMode = "Regex"
+
+	// the patterns are regexpressions, which have to match the whole names
+	MatchModeAnchoredRegex MatchMode = "AnchoredRegex"
+
+	// the patterns are globs, "*" matches any sequence of characters and
+	// "?" matches a single character, e.g. "team-*"
+	MatchModeGlob MatchMode = "Glob"
+
+	// the patterns are the exact names of the namespaces
+	MatchModeExact MatchMode = "Exact"
+)
+
+// the namespaces, which are targeted by a global object
+type NamespaceSelector struct {
+
+	// the way the patterns of both lists are compared with the names of the namespaces,
+	// defaults to Regex
+	// +kubebuilder:default=Regex
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	MatchMode MatchMode `json:"matchMode,omitempty"`
+
+	// the patterns of the namespaces, which are targeted
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	MatchRegex []string `json:"matchRegex,omitempty"`
+
+	// the patterns of the namespaces, which are never targeted, even if they match
+	// the list matchRegex
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	AvoidRegex []string `json:"avoidRegex,omitempty"`
+}
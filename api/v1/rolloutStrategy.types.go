@@ -0,0 +1,77 @@
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// struct which contains the information about a staged rollout of the replicated objects
+type RolloutStrategy struct {
+
+	// the waves of the rollout, a namespace belongs to the first wave, which matches
+	// its name, the namespaces, which match no wave, are updated in a final wave
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Waves []RolloutWave `json:"waves,omitempty"`
+
+	// the maximum number of namespaces, which are updated in one step,
+	// 0 updates the whole wave at once
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	BatchSize int32 `json:"batchSize,omitempty"`
+
+	// the time to wait after a wave was updated, before the next wave starts
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	PauseBetweenWaves metav1.Duration `json:"pauseBetweenWaves,omitempty"`
+
+	// the time to wait between two batches of the same wave, defaults to 5s
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	BatchInterval *metav1.Duration `json:"batchInterval,omitempty"`
+
+	// the way the patterns of the waves are compared with the names of the namespaces,
+	// defaults to Regex
+	// +kubebuilder:default=Regex
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	MatchMode MatchMode `json:"matchMode,omitempty"`
+}
+
+// struct which contains the information about a single wave of a staged rollout
+type RolloutWave struct {
+	Name string `json:"name"`
+
+	// the regexpressions of the namespaces, which belong to the wave
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	MatchRegex []string `json:"matchRegex"`
+}
+
+// the progress of a staged rollout
+type RolloutStatus struct {
+
+	// the content hash, which is rolled out
+	// +optional
+	ContentHash string `json:"contentHash,omitempty"`
+
+	// the index of the wave, which is currently updated
+	CurrentWave int32 `json:"currentWave"`
+
+	// the name of the wave, which is currently updated
+	// +optional
+	CurrentWaveName string `json:"currentWaveName,omitempty"`
+
+	// the next wave is not started before this time
+	// +optional
+	PausedUntil *metav1.Time `json:"pausedUntil,omitempty"`
+
+	// the next batch of the current wave is not started before this time
+	// +optional
+	NextBatchAfter *metav1.Time `json:"nextBatchAfter,omitempty"`
+
+	// the number of matching namespaces, which contain the current data
+	UpdatedNamespaces int32 `json:"updatedNamespaces"`
+
+	// the number of matching namespaces
+	TotalNamespaces int32 `json:"totalNamespaces"`
+}
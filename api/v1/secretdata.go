@@ -0,0 +1,82 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+)
+
+// the placeholder, which replaces the values of the global secrets in the logs
+const Redacted = "[REDACTED]"
+
+// the annotation of kubectl apply, which contains the whole applied object, including the data
+const annotationLastAppliedConfiguration = "kubectl.kubernetes.io/last-applied-configuration"
+
+// the base64 encoded data of a global secret
+//
+// the values are never printed, neither with fmt nor with a logr logger, only
+// the keys are visible, the json encoding is unchanged
+type SecretData map[string]string
+
+// get a copy of the data, whose values are replaced by [Redacted]
+func (d SecretData) Redacted() map[string]string {
+	if d == nil {
+		return nil
+	}
+	var redacted = make(map[string]string, len(d))
+	for k := range d {
+		redacted[k] = Redacted
+	}
+	return redacted
+}
+
+// implements fmt.Stringer
+func (d SecretData) String() string {
+	return fmt.Sprint(d.Redacted())
+}
+
+// implements fmt.GoStringer
+func (d SecretData) GoString() string {
+	return fmt.Sprintf("%#v", d.Redacted())
+}
+
+// implements logr.Marshaler
+func (d SecretData) MarshalLog() interface{} {
+	return d.Redacted()
+}
+
+// implements logr.Marshaler, the data and the last applied configuration are redacted
+func (in *GlobalSecret) MarshalLog() interface{} {
+	if in == nil {
+		return nil
+	}
+	var redacted = in.DeepCopy()
+	redacted.Spec.Data = redacted.Spec.Data.Redacted()
+	if _, ok := redacted.Annotations[annotationLastAppliedConfiguration]; ok {
+		redacted.Annotations[annotationLastAppliedConfiguration] = Redacted
+	}
+	redacted.ManagedFields = nil
+	return redacted
+}
+
+// implements fmt.Stringer, see MarshalLog
+func (in *GlobalSecret) String() string {
+	if in == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("%+v", *in.MarshalLog().(*GlobalSecret))
+}
@@ -0,0 +1,13 @@
+package v1
+
+// the state of a replicated object in one of the matching namespaces
+type TargetStatus struct {
+	Namespace string `json:"namespace"`
+
+	// the content hash of the existing replicated object, empty if it does not exist
+	// +optional
+	ContentHash string `json:"contentHash,omitempty"`
+
+	// whether the replicated object exists and contains the current data
+	InSync bool `json:"inSync"`
+}
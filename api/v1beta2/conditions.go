@@ -0,0 +1,59 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+// the condition types, which are set in the status of the global objects
+const (
+	// all replicated objects exist in the matching namespaces and contain the current data
+	ConditionSynced string = "Synced"
+
+	// the replication is suspended via spec.suspend
+	ConditionSuspended string = "Suspended"
+
+	// the namespace regexpressions or the patterns of the waves of the rollout
+	// strategy can not be compiled, no replicated objects are touched, until the
+	// spec is fixed
+	ConditionInvalidNamespaceSelector string = "InvalidNamespaceSelector"
+
+	// the global object violates a GlobalReplicationPolicy, no replicated objects
+	// are touched, until the violation is resolved
+	ConditionPolicyViolation string = "PolicyViolation"
+
+	// the global object is in dry run mode, the planned changes are reported in
+	// status.plan, but not applied
+	ConditionDryRun string = "DryRun"
+
+	// a matching namespace already contains an object with the same name, which
+	// is not owned by the global object, the object is never overwritten
+	ConditionConflict string = "Conflict"
+)
+
+// the reasons of the conditions
+const (
+	ReasonSynced     string = "Synced"
+	ReasonDrifted    string = "Drifted"
+	ReasonSuspended  string = "Suspended"
+	ReasonActive     string = "Active"
+	ReasonRolling    string = "RollingOut"
+	ReasonInvalid    string = "InvalidRegex"
+	ReasonValid      string = "Valid"
+	ReasonViolated   string = "PolicyViolated"
+	ReasonCompliant  string = "Compliant"
+	ReasonDryRun     string = "DryRun"
+	ReasonUnowned    string = "UnownedObject"
+	ReasonNoConflict string = "NoConflict"
+)
@@ -0,0 +1,179 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/jnnkrdb/configrdb/api/v1"
+)
+
+// the conversions of the types, which are shared by both kinds, the
+// slices are copied, so the converted objects do not share memory
+
+func convertNamespacesTo(src NamespacesRegex) v1.NamespaceSelector {
+	return v1.NamespaceSelector{
+		MatchMode:  v1.MatchMode(src.MatchMode),
+		MatchRegex: copyStrings(src.MatchRegex),
+		AvoidRegex: copyStrings(src.AvoidRegex),
+	}
+}
+
+func convertNamespacesFrom(src v1.NamespaceSelector) NamespacesRegex {
+	return NamespacesRegex{
+		MatchMode:  MatchMode(src.MatchMode),
+		MatchRegex: copyStrings(src.MatchRegex),
+		AvoidRegex: copyStrings(src.AvoidRegex),
+	}
+}
+
+func convertRolloutStrategyTo(src *RolloutStrategy) *v1.RolloutStrategy {
+	if src == nil {
+		return nil
+	}
+	var dst = &v1.RolloutStrategy{
+		BatchSize:         src.BatchSize,
+		PauseBetweenWaves: src.PauseBetweenWaves,
+		BatchInterval:     copyDuration(src.BatchInterval),
+		MatchMode:         v1.MatchMode(src.MatchMode),
+	}
+	for _, wave := range src.Waves {
+		dst.Waves = append(dst.Waves, v1.RolloutWave{Name: wave.Name, MatchRegex: copyStrings(wave.MatchRegex)})
+	}
+	return dst
+}
+
+func convertRolloutStrategyFrom(src *v1.RolloutStrategy) *RolloutStrategy {
+	if src == nil {
+		return nil
+	}
+	var dst = &RolloutStrategy{
+		BatchSize:         src.BatchSize,
+		PauseBetweenWaves: src.PauseBetweenWaves,
+		BatchInterval:     copyDuration(src.BatchInterval),
+		MatchMode:         MatchMode(src.MatchMode),
+	}
+	for _, wave := range src.Waves {
+		dst.Waves = append(dst.Waves, RolloutWave{Name: wave.Name, MatchRegex: copyStrings(wave.MatchRegex)})
+	}
+	return dst
+}
+
+func convertRolloutStatusTo(src *RolloutStatus) *v1.RolloutStatus {
+	if src == nil {
+		return nil
+	}
+	return &v1.RolloutStatus{
+		ContentHash:       src.ContentHash,
+		CurrentWave:       src.CurrentWave,
+		CurrentWaveName:   src.CurrentWaveName,
+		PausedUntil:       src.PausedUntil.DeepCopy(),
+		NextBatchAfter:    src.NextBatchAfter.DeepCopy(),
+		UpdatedNamespaces: src.UpdatedNamespaces,
+		TotalNamespaces:   src.TotalNamespaces,
+	}
+}
+
+func convertRolloutStatusFrom(src *v1.RolloutStatus) *RolloutStatus {
+	if src == nil {
+		return nil
+	}
+	return &RolloutStatus{
+		ContentHash:       src.ContentHash,
+		CurrentWave:       src.CurrentWave,
+		CurrentWaveName:   src.CurrentWaveName,
+		PausedUntil:       src.PausedUntil.DeepCopy(),
+		NextBatchAfter:    src.NextBatchAfter.DeepCopy(),
+		UpdatedNamespaces: src.UpdatedNamespaces,
+		TotalNamespaces:   src.TotalNamespaces,
+	}
+}
+
+func convertPlanTo(src *ReplicationPlan) *v1.ReplicationPlan {
+	if src == nil {
+		return nil
+	}
+	return &v1.ReplicationPlan{
+		ContentHash: src.ContentHash,
+		Matched:     src.Matched,
+		Avoided:     src.Avoided,
+		Create:      copyStrings(src.Create),
+		Update:      copyStrings(src.Update),
+		Delete:      copyStrings(src.Delete),
+		Conflicts:   copyStrings(src.Conflicts),
+	}
+}
+
+func convertPlanFrom(src *v1.ReplicationPlan) *ReplicationPlan {
+	if src == nil {
+		return nil
+	}
+	return &ReplicationPlan{
+		ContentHash: src.ContentHash,
+		Matched:     src.Matched,
+		Avoided:     src.Avoided,
+		Create:      copyStrings(src.Create),
+		Update:      copyStrings(src.Update),
+		Delete:      copyStrings(src.Delete),
+		Conflicts:   copyStrings(src.Conflicts),
+	}
+}
+
+func copyStrings(src []string) []string {
+	if src == nil {
+		return nil
+	}
+	return append(make([]string, 0, len(src)), src...)
+}
+
+func copyStringMap(src map[string]string) map[string]string {
+	if src == nil {
+		return nil
+	}
+	var dst = make(map[string]string, len(src))
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
+
+func copyInt32(src *int32) *int32 {
+	if src == nil {
+		return nil
+	}
+	var dst = *src
+	return &dst
+}
+
+func copyDuration(src *metav1.Duration) *metav1.Duration {
+	if src == nil {
+		return nil
+	}
+	var dst = *src
+	return &dst
+}
+
+func copyConditions(src []metav1.Condition) []metav1.Condition {
+	if src == nil {
+		return nil
+	}
+	var dst = make([]metav1.Condition, len(src))
+	for i := range src {
+		src[i].DeepCopyInto(&dst[i])
+	}
+	return dst
+}
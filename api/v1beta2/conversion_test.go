@@ -0,0 +1,137 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	v1 "github.com/jnnkrdb/configrdb/api/v1"
+)
+
+// fill the fields, which are shared by both kinds
+func fillMeta(obj metav1.Object) {
+	obj.SetName("global")
+	obj.SetNamespace("default")
+	obj.SetUID("uid")
+	obj.SetGeneration(4)
+	obj.SetLabels(map[string]string{"team": "platform"})
+	obj.SetAnnotations(map[string]string{AnnotationAllowProtectedNamespaces: "true"})
+	obj.SetFinalizers([]string{FinalizerGlobal})
+}
+
+var (
+	testLimit     int32 = 3
+	testNamespace       = NamespacesRegex{MatchMode: MatchModeGlob, AvoidRegex: []string{"kube-*"}, MatchRegex: []string{"team-*", "shared"}}
+	testStrategy        = &RolloutStrategy{
+		Waves:             []RolloutWave{{Name: "dev", MatchRegex: []string{"-dev$"}}, {Name: "staging", MatchRegex: []string{"-staging$"}}},
+		BatchSize:         5,
+		PauseBetweenWaves: metav1.Duration{Duration: 10 * time.Minute},
+		BatchInterval:     &metav1.Duration{Duration: time.Minute},
+		MatchMode:         MatchModeAnchoredRegex,
+	}
+	testRollout = &RolloutStatus{
+		ContentHash: "hash", CurrentWave: 1, CurrentWaveName: "staging",
+		PausedUntil:       &metav1.Time{Time: time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)},
+		NextBatchAfter:    &metav1.Time{Time: time.Date(2023, 5, 1, 11, 0, 0, 0, time.UTC)},
+		UpdatedNamespaces: 3, TotalNamespaces: 7,
+	}
+	testPlan       = &ReplicationPlan{ContentHash: "hash", Matched: 3, Avoided: 2, Create: []string{"a"}, Update: []string{"b"}, Delete: []string{"c"}, Conflicts: []string{"d"}}
+	testConditions = []metav1.Condition{{Type: ConditionSynced, Status: metav1.ConditionFalse, Reason: ReasonDrifted, Message: "1 replicated objects drifted", ObservedGeneration: 4}}
+)
+
+func TestGlobalConfigRoundTrip(t *testing.T) {
+	var gc = &GlobalConfig{
+		Spec: GlobalConfigSpec{
+			Namespaces: testNamespace, Data: map[string]string{"key": "value"},
+			Rollout: true, Suspend: true, DryRun: true,
+			RolloutStrategy: testStrategy, RevisionHistoryLimit: &testLimit,
+			ResyncInterval: &metav1.Duration{Duration: time.Minute},
+		},
+		Status: GlobalConfigStatus{
+			DeployedConfigMaps: []DeployedConfigMap{{Namespace: "team-a", ContentHash: "hash", InSync: true}, {Namespace: "team-b"}},
+			CurrentRevision:    2, Rollout: testRollout, Plan: testPlan, Conditions: testConditions,
+		},
+	}
+	fillMeta(gc)
+
+	var hub = &v1.GlobalConfig{}
+	if err := gc.ConvertTo(hub); err != nil {
+		t.Fatal(err)
+	}
+	if hub.Spec.Namespaces.MatchRegex[1] != "shared" || len(hub.Status.Targets) != 2 || !hub.Status.Targets[0].InSync {
+		t.Errorf("unexpected hub %+v", hub)
+	}
+
+	var back = &GlobalConfig{}
+	if err := back.ConvertFrom(hub); err != nil {
+		t.Fatal(err)
+	}
+	if !equality.Semantic.DeepEqual(gc, back) {
+		t.Errorf("the round trip v1beta2 -> v1 -> v1beta2 changed the object:\n%+v\n%+v", gc, back)
+	}
+
+	var hubBack = &v1.GlobalConfig{}
+	if err := back.ConvertTo(hubBack); err != nil {
+		t.Fatal(err)
+	}
+	if !equality.Semantic.DeepEqual(hub, hubBack) {
+		t.Errorf("the round trip v1 -> v1beta2 -> v1 changed the object:\n%+v\n%+v", hub, hubBack)
+	}
+}
+
+func TestGlobalSecretRoundTrip(t *testing.T) {
+	var gs = &GlobalSecret{
+		Spec: GlobalSecretSpec{
+			Namespaces: testNamespace, Type: "kubernetes.io/dockerconfigjson", Data: map[string]string{".dockerconfigjson": "e30="},
+			Rollout: true, Suspend: true, DryRun: true,
+			RolloutStrategy: testStrategy, RevisionHistoryLimit: &testLimit,
+			ResyncInterval: &metav1.Duration{Duration: time.Minute},
+		},
+		Status: GlobalSecretStatus{
+			DeployedSecrets: []DeployedSecret{{Namespace: "team-a", ContentHash: "hash", InSync: true}},
+			CurrentRevision: 2, Rollout: testRollout, Plan: testPlan, Conditions: testConditions,
+		},
+	}
+	fillMeta(gs)
+
+	var hub = &v1.GlobalSecret{}
+	if err := gs.ConvertTo(hub); err != nil {
+		t.Fatal(err)
+	}
+	if hub.Spec.Type != "kubernetes.io/dockerconfigjson" {
+		t.Errorf("unexpected type %q", hub.Spec.Type)
+	}
+
+	var back = &GlobalSecret{}
+	if err := back.ConvertFrom(hub); err != nil {
+		t.Fatal(err)
+	}
+	if !equality.Semantic.DeepEqual(gs, back) {
+		t.Errorf("the round trip v1beta2 -> v1 -> v1beta2 changed the object:\n%+v\n%+v", gs, back)
+	}
+
+	// the converted object must not share memory with the source
+	hub.Spec.Namespaces.MatchRegex[0] = "changed"
+	hub.Spec.Data[".dockerconfigjson"] = "changed"
+	if back.Spec.Namespaces.MatchRegex[0] == "changed" || back.Spec.Data[".dockerconfigjson"] == "changed" {
+		t.Error("the converted object shares memory with the source")
+	}
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the number of former revisions, which are kept, if a global object does not set
+// the field revisionHistoryLimit
+const DefaultRevisionHistoryLimit int32 = 10
+
+// the time between two batches of the same wave, if a rollout strategy does not set
+// the field batchInterval
+const DefaultRolloutBatchInterval = 5 * time.Second
+
+// the type of the replicated secrets, if a globalsecret does not set the field type
+const DefaultSecretType = "Opaque"
+
+// apply the defaults to the namespace regexpressions
+//
+// the match mode defaults to Regex and a missing list defaults to an empty list,
+// which matches no namespace, the patterns are normalised, so the stored object
+// shows the patterns, which are actually compared with the namespaces
+func (nsr *NamespacesRegex) Default() {
+	if nsr.MatchMode == "" {
+		nsr.MatchMode = MatchModeRegex
+	}
+	nsr.AvoidRegex = normalizePatterns(nsr.AvoidRegex)
+	nsr.MatchRegex = normalizePatterns(nsr.MatchRegex)
+}
+
+// apply the defaults to a rollout strategy and normalise the patterns of its waves
+func (rs *RolloutStrategy) Default() {
+	if rs.MatchMode == "" {
+		rs.MatchMode = MatchModeRegex
+	}
+	if rs.BatchInterval == nil {
+		rs.BatchInterval = &metav1.Duration{Duration: DefaultRolloutBatchInterval}
+	}
+	for i := range rs.Waves {
+		rs.Waves[i].MatchRegex = normalizePatterns(rs.Waves[i].MatchRegex)
+	}
+}
+
+// remove the surrounding whitespaces, the empty patterns and the duplicates of
+// a list of patterns, the order of the remaining patterns is kept
+//
+// the result is never nil, so a missing list is stored as an empty list
+func normalizePatterns(patterns []string) []string {
+	var normalized = make([]string, 0, len(patterns))
+	var seen = make(map[string]bool, len(patterns))
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" || seen[p] {
+			continue
+		}
+		seen[p] = true
+		normalized = append(normalized, p)
+	}
+	return normalized
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizePatterns(t *testing.T) {
+	for _, tc := range []struct {
+		patterns []string
+		want     []string
+	}{
+		{nil, []string{}},
+		{[]string{" team-a ", "", "  ", "team-b"}, []string{"team-a", "team-b"}},
+		{[]string{"^prod", "team", "^prod ", "team"}, []string{"^prod", "team"}},
+	} {
+		if got := normalizePatterns(tc.patterns); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("normalizePatterns(%q) = %q, want %q", tc.patterns, got, tc.want)
+		}
+	}
+}
+
+func TestDefault(t *testing.T) {
+	var gs = &GlobalSecret{}
+	gs.Spec.Namespaces.MatchRegex = []string{"team-a ", "team-a"}
+	gs.Spec.RolloutStrategy = &RolloutStrategy{Waves: []RolloutWave{{Name: "canary", MatchRegex: []string{" canary"}}}}
+
+	if err := (&globalSecretDefaulter{}).Default(context.Background(), gs); err != nil {
+		t.Fatal(err)
+	}
+
+	if gs.Spec.Namespaces.MatchMode != MatchModeRegex {
+		t.Errorf("expected the match mode %s, got %q", MatchModeRegex, gs.Spec.Namespaces.MatchMode)
+	}
+	if gs.Spec.Namespaces.AvoidRegex == nil || len(gs.Spec.Namespaces.AvoidRegex) != 0 {
+		t.Errorf("expected an empty avoidregex, got %#v", gs.Spec.Namespaces.AvoidRegex)
+	}
+	if !reflect.DeepEqual(gs.Spec.Namespaces.MatchRegex, []string{"team-a"}) {
+		t.Errorf("expected the normalised matchregex, got %q", gs.Spec.Namespaces.MatchRegex)
+	}
+	if rs := gs.Spec.RolloutStrategy; rs.MatchMode != MatchModeRegex || rs.BatchInterval == nil || rs.BatchInterval.Duration != DefaultRolloutBatchInterval {
+		t.Errorf("expected the defaults of the rollout strategy, got %+v", rs)
+	}
+	if !reflect.DeepEqual(gs.Spec.RolloutStrategy.Waves[0].MatchRegex, []string{"canary"}) {
+		t.Errorf("expected the normalised wave, got %q", gs.Spec.RolloutStrategy.Waves[0].MatchRegex)
+	}
+	if gs.Spec.Type != DefaultSecretType {
+		t.Errorf("expected the type %s, got %q", DefaultSecretType, gs.Spec.Type)
+	}
+	if gs.Spec.RevisionHistoryLimit == nil || *gs.Spec.RevisionHistoryLimit != DefaultRevisionHistoryLimit {
+		t.Errorf("expected the revision history limit %d, got %v", DefaultRevisionHistoryLimit, gs.Spec.RevisionHistoryLimit)
+	}
+
+	// explicit values are kept
+	var limit int32
+	var gc = &GlobalConfig{}
+	gc.Spec.Namespaces.MatchMode = MatchModeGlob
+	gc.Spec.RevisionHistoryLimit = &limit
+	if err := (&globalConfigDefaulter{}).Default(context.Background(), gc); err != nil {
+		t.Fatal(err)
+	}
+	if gc.Spec.Namespaces.MatchMode != MatchModeGlob || *gc.Spec.RevisionHistoryLimit != 0 {
+		t.Errorf("expected the explicit values to be kept, got %+v", gc.Spec)
+	}
+}
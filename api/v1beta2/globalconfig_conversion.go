@@ -0,0 +1,91 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	v1 "github.com/jnnkrdb/configrdb/api/v1"
+)
+
+var _ conversion.Convertible = &GlobalConfig{}
+
+// ConvertTo converts this GlobalConfig to the Hub version (v1).
+func (src *GlobalConfig) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1.GlobalConfig)
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	dst.Spec = v1.GlobalConfigSpec{
+		Namespaces:           convertNamespacesTo(src.Spec.Namespaces),
+		Data:                 copyStringMap(src.Spec.Data),
+		Rollout:              src.Spec.Rollout,
+		Suspend:              src.Spec.Suspend,
+		DryRun:               src.Spec.DryRun,
+		RolloutStrategy:      convertRolloutStrategyTo(src.Spec.RolloutStrategy),
+		RevisionHistoryLimit: copyInt32(src.Spec.RevisionHistoryLimit),
+		ResyncInterval:       copyDuration(src.Spec.ResyncInterval),
+	}
+
+	dst.Status = v1.GlobalConfigStatus{
+		CurrentRevision: src.Status.CurrentRevision,
+		Rollout:         convertRolloutStatusTo(src.Status.Rollout),
+		Plan:            convertPlanTo(src.Status.Plan),
+		Conditions:      copyConditions(src.Status.Conditions),
+	}
+	for _, deployed := range src.Status.DeployedConfigMaps {
+		dst.Status.Targets = append(dst.Status.Targets, v1.TargetStatus{
+			Namespace:   deployed.Namespace,
+			ContentHash: deployed.ContentHash,
+			InSync:      deployed.InSync,
+		})
+	}
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1) to this version.
+func (dst *GlobalConfig) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1.GlobalConfig)
+
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+
+	dst.Spec = GlobalConfigSpec{
+		Namespaces:           convertNamespacesFrom(src.Spec.Namespaces),
+		Data:                 copyStringMap(src.Spec.Data),
+		Rollout:              src.Spec.Rollout,
+		Suspend:              src.Spec.Suspend,
+		DryRun:               src.Spec.DryRun,
+		RolloutStrategy:      convertRolloutStrategyFrom(src.Spec.RolloutStrategy),
+		RevisionHistoryLimit: copyInt32(src.Spec.RevisionHistoryLimit),
+		ResyncInterval:       copyDuration(src.Spec.ResyncInterval),
+	}
+
+	dst.Status = GlobalConfigStatus{
+		CurrentRevision: src.Status.CurrentRevision,
+		Rollout:         convertRolloutStatusFrom(src.Status.Rollout),
+		Plan:            convertPlanFrom(src.Status.Plan),
+		Conditions:      copyConditions(src.Status.Conditions),
+	}
+	for _, target := range src.Status.Targets {
+		dst.Status.DeployedConfigMaps = append(dst.Status.DeployedConfigMaps, DeployedConfigMap{
+			Namespace:   target.Namespace,
+			ContentHash: target.ContentHash,
+			InSync:      target.InSync,
+		})
+	}
+	return nil
+}
@@ -38,6 +38,43 @@ type GlobalConfigSpec struct {
 
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	Data map[string]string `json:"data"`
+
+	// restart the Deployments, StatefulSets and DaemonSets, which consume the
+	// replicated configmap, whenever the data changes
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Rollout bool `json:"rollout,omitempty"`
+
+	// stop the replication, while suspended, the existing configmaps are neither
+	// created, updated nor removed, but the drift is still reported in the status
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Suspend bool `json:"suspend,omitempty"`
+
+	// only calculate the changes to the configmaps and report them in status.plan,
+	// without creating, updating or removing any configmaps
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// update the outdated configmaps in waves and batches, instead of
+	// updating all namespaces at once
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	RolloutStrategy *RolloutStrategy `json:"rolloutStrategy,omitempty"`
+
+	// the number of former revisions of the data, which are kept for a rollback,
+	// defaults to 10, 0 disables the history
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// the interval, in which the configmaps are checked again without an event, overrides
+	// the interval of the operator, 0 disables the periodic check
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	ResyncInterval *metav1.Duration `json:"resyncInterval,omitempty"`
 }
 
 // GlobalConfigStatus defines the observed state of GlobalConfig
@@ -48,11 +85,35 @@ type GlobalConfigStatus struct {
 	// +operator-sdk:csv:customresourcedefinitions:type=status
 	DeployedConfigMaps []DeployedConfigMap `json:"deployedconfigmaps,omitempty"`
 
+	// the number of the revision, which contains the current data
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	CurrentRevision int64 `json:"currentRevision,omitempty"`
+
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+
+	// the changes, which would be applied, if the dry run was disabled
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Plan *ReplicationPlan `json:"plan,omitempty"`
+
 	// +operator-sdk:csv:customresourcedefinitions:type=status
 	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
 }
 
-type DeployedConfigMap struct{}
+// the state of a replicated configmap in one of the matching namespaces
+type DeployedConfigMap struct {
+	Namespace string `json:"namespace"`
+
+	// the content hash of the existing configmap, empty if it does not exist
+	// +optional
+	ContentHash string `json:"contenthash,omitempty"`
+
+	// whether the configmap exists and contains the current data
+	InSync bool `json:"insync"`
+}
 
 // GlobalConfig is the Schema for the globalconfigs API
 // +kubebuilder:subresource:status
@@ -0,0 +1,88 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// SetupWebhookWithManager registers the defaulting and the validating webhook of the globalconfigs
+func (r *GlobalConfig) SetupWebhookWithManager(mgr ctrl.Manager, v *GlobalValidator) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithDefaulter(&globalConfigDefaulter{}).
+		WithValidator(&globalConfigValidator{v}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-globals-jnnkrdb-de-v1beta2-globalconfig,mutating=true,failurePolicy=fail,sideEffects=None,groups=globals.jnnkrdb.de,resources=globalconfigs,verbs=create;update,versions=v1beta2,name=mglobalconfig.kb.io,admissionReviewVersions=v1
+
+type globalConfigDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &globalConfigDefaulter{}
+
+// Default implements webhook.CustomDefaulter
+func (d *globalConfigDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	gc, ok := obj.(*GlobalConfig)
+	if !ok {
+		return fmt.Errorf("expected a GlobalConfig, got %T", obj)
+	}
+	gc.Spec.Namespaces.Default()
+	if gc.Spec.RolloutStrategy != nil {
+		gc.Spec.RolloutStrategy.Default()
+	}
+	if gc.Spec.RevisionHistoryLimit == nil {
+		var limit = DefaultRevisionHistoryLimit
+		gc.Spec.RevisionHistoryLimit = &limit
+	}
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-globals-jnnkrdb-de-v1beta2-globalconfig,mutating=false,failurePolicy=fail,sideEffects=None,groups=globals.jnnkrdb.de,resources=globalconfigs,verbs=create;update,versions=v1beta2,name=vglobalconfig.kb.io,admissionReviewVersions=v1
+
+type globalConfigValidator struct {
+	*GlobalValidator
+}
+
+var _ webhook.CustomValidator = &globalConfigValidator{}
+
+// ValidateCreate implements webhook.CustomValidator
+func (v *globalConfigValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	gc, ok := obj.(*GlobalConfig)
+	if !ok {
+		return fmt.Errorf("expected a GlobalConfig, got %T", obj)
+	}
+	if err := validateRolloutStrategy(gc.Spec.RolloutStrategy); err != nil {
+		return err
+	}
+	return v.validateTargetNamespaces(ctx, gc, gc.Spec.Namespaces, "configmaps", gc.Spec.Rollout)
+}
+
+// ValidateUpdate implements webhook.CustomValidator
+func (v *globalConfigValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	return v.ValidateCreate(ctx, newObj)
+}
+
+// ValidateDelete implements webhook.CustomValidator
+func (v *globalConfigValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GlobalReplicationPolicySpec defines the constraints of the GlobalConfigs and GlobalSecrets
+//
+// a global object, which violates a policy, does not touch its replicated objects, so
+// the copies, which already exist in the namespaces, stay, when a policy is created or
+// tightened, e.g. by a forbidden secret type or a smaller list of target namespaces,
+// they are removed, when the global object is changed to comply or is deleted
+type GlobalReplicationPolicySpec struct {
+
+	// regexpressions of the namespaces, in which global objects may be created,
+	// they are anchored like the MatchMode AnchoredRegex, so they match the whole
+	// name, an empty list allows all namespaces
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	SourceNamespaces []string `json:"sourceNamespaces,omitempty"`
+
+	// the namespaces, which may be targeted, a global object, which matches
+	// any other namespace, violates the policy
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	TargetNamespaces *NamespacesRegex `json:"targetNamespaces,omitempty"`
+
+	// the types of the GlobalSecrets, which must not be replicated, an empty type is
+	// compared as Opaque, the default type of the secrets
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	ForbiddenSecretTypes []string `json:"forbiddenSecretTypes,omitempty"`
+
+	// the maximum number of namespaces, a global object may be replicated into
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	MaxFanOut *int32 `json:"maxFanOut,omitempty"`
+
+	// the keys of the labels, which every global object must carry
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	RequiredLabels []string `json:"requiredLabels,omitempty"`
+}
+
+// GlobalReplicationPolicy is the Schema for the globalreplicationpolicies API
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=globalreplicationpolicies,scope=Cluster,shortName=grp;grps
+type GlobalReplicationPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GlobalReplicationPolicySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// GlobalReplicationPolicyList contains a list of GlobalReplicationPolicy
+type GlobalReplicationPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GlobalReplicationPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&GlobalReplicationPolicy{}, &GlobalReplicationPolicyList{})
+}
+
+// get the violations of the policy by a global object
+//
+// secretType is empty for GlobalConfigs, targets are the names of the namespaces,
+// which are matched by the global object, an invalid policy is reported as violation,
+// so a broken policy can not allow everything
+func (p *GlobalReplicationPolicy) Violations(obj metav1.Object, secretType string, targets []string) (violations []string) {
+
+	var violate = func(format string, args ...interface{}) {
+		violations = append(violations, fmt.Sprintf("policy %s: ", p.Name)+fmt.Sprintf(format, args...))
+	}
+
+	if len(p.Spec.SourceNamespaces) > 0 {
+		var allowed bool
+		for _, expr := range p.Spec.SourceNamespaces {
+			re, err := MatchModeAnchoredRegex.compile(expr)
+			if err != nil {
+				violate("invalid sourceNamespaces regex %q: %v", expr, err)
+				continue
+			}
+			if re.MatchString(obj.GetNamespace()) {
+				allowed = true
+			}
+		}
+		if !allowed {
+			violate("the namespace %s is not an allowed source namespace", obj.GetNamespace())
+		}
+	}
+
+	if p.Spec.TargetNamespaces != nil {
+		if cnsr, err := p.Spec.TargetNamespaces.Compile(); err != nil {
+			violate("invalid targetNamespaces: %v", err)
+		} else {
+			var denied []string
+			for _, ns := range targets {
+				if !cnsr.Matches(ns) {
+					denied = append(denied, ns)
+				}
+			}
+			switch {
+			case len(denied) > 5:
+				violate("the namespaces %s and %d more are not allowed target namespaces", strings.Join(denied[:5], ", "), len(denied)-5)
+			case len(denied) > 0:
+				violate("the namespaces %s are not allowed target namespaces", strings.Join(denied, ", "))
+			}
+		}
+	}
+
+	// a globalsecret without type replicates secrets of the default type
+	if _, ok := obj.(*GlobalSecret); ok && secretType == "" {
+		secretType = DefaultSecretType
+	}
+	for _, forbidden := range p.Spec.ForbiddenSecretTypes {
+		if forbidden == "" {
+			forbidden = DefaultSecretType
+		}
+		if secretType != "" && secretType == forbidden {
+			violate("the secret type %s is forbidden", secretType)
+		}
+	}
+
+	if p.Spec.MaxFanOut != nil && len(targets) > int(*p.Spec.MaxFanOut) {
+		violate("%d target namespaces exceed the maximum fan-out of %d", len(targets), *p.Spec.MaxFanOut)
+	}
+
+	for _, key := range p.Spec.RequiredLabels {
+		if _, ok := obj.GetLabels()[key]; !ok {
+			violate("the required label %s is missing", key)
+		}
+	}
+	return
+}
@@ -0,0 +1,112 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPolicyViolations(t *testing.T) {
+	var maxFanOut int32 = 2
+	var policy = &GlobalReplicationPolicy{
+		Spec: GlobalReplicationPolicySpec{
+			SourceNamespaces:     []string{"platform-.*"},
+			TargetNamespaces:     &NamespacesRegex{AvoidRegex: []string{"^kube-"}, MatchRegex: []string{"."}},
+			ForbiddenSecretTypes: []string{"kubernetes.io/service-account-token"},
+			MaxFanOut:            &maxFanOut,
+			RequiredLabels:       []string{"team"},
+		},
+	}
+	policy.Name = "restricted"
+
+	var compliant = &GlobalSecret{}
+	compliant.Namespace = "platform-secrets"
+	compliant.Labels = map[string]string{"team": "platform"}
+	if v := policy.Violations(compliant, "Opaque", []string{"team-a", "team-b"}); len(v) != 0 {
+		t.Errorf("expected no violations, got %q", v)
+	}
+
+	var violating = &GlobalSecret{}
+	violating.Namespace = "default"
+	var violations = policy.Violations(violating, "kubernetes.io/service-account-token", []string{"team-a", "kube-system", "team-b"})
+	for _, want := range []string{
+		"not an allowed source namespace",
+		"kube-system are not allowed target namespaces",
+		"secret type kubernetes.io/service-account-token is forbidden",
+		"exceed the maximum fan-out of 2",
+		"required label team is missing",
+	} {
+		if !strings.Contains(strings.Join(violations, "\n"), want) {
+			t.Errorf("expected the violation %q, got %q", want, violations)
+		}
+	}
+
+	// the secret types are not checked for globalconfigs
+	var gc = &GlobalConfig{}
+	gc.Namespace = "platform-config"
+	gc.Labels = map[string]string{"team": "platform"}
+	if v := policy.Violations(gc, "", []string{"team-a"}); len(v) != 0 {
+		t.Errorf("expected no violations, got %q", v)
+	}
+
+	// the source namespaces match the whole name
+	policy.Spec.SourceNamespaces = []string{"platform"}
+	for _, ns := range []string{"platform-secrets", "not-platform"} {
+		compliant.Namespace = ns
+		if v := policy.Violations(compliant, "Opaque", nil); len(v) != 1 || !strings.Contains(v[0], "not an allowed source namespace") {
+			t.Errorf("%s: expected the source namespace violation, got %q", ns, v)
+		}
+	}
+	compliant.Namespace = "platform"
+	if v := policy.Violations(compliant, "Opaque", nil); len(v) != 0 {
+		t.Errorf("expected no violations, got %q", v)
+	}
+
+	// an invalid policy must not allow everything
+	policy.Spec.SourceNamespaces = []string{"platform-(a"}
+	if v := policy.Violations(compliant, "Opaque", nil); len(v) == 0 {
+		t.Error("expected a violation of the invalid policy")
+	}
+
+	// a pattern can not escape the anchors of the source namespaces
+	policy.Spec.SourceNamespaces = []string{"platform)|(?:.*"}
+	compliant.Namespace = "team-a"
+	if v := policy.Violations(compliant, "Opaque", nil); len(v) == 0 {
+		t.Error("expected a violation of the escaping pattern")
+	}
+}
+
+func TestForbiddenDefaultSecretType(t *testing.T) {
+	for _, forbidden := range []string{"Opaque", ""} {
+		var policy = &GlobalReplicationPolicy{Spec: GlobalReplicationPolicySpec{ForbiddenSecretTypes: []string{forbidden}}}
+		policy.Name = "no-opaque"
+
+		// a globalsecret without type replicates opaque secrets
+		for _, secretType := range []string{"", "Opaque"} {
+			if v := policy.Violations(&GlobalSecret{}, secretType, nil); len(v) != 1 || !strings.Contains(v[0], "secret type Opaque is forbidden") {
+				t.Errorf("forbidden %q, type %q: expected the violation of the type, got %q", forbidden, secretType, v)
+			}
+		}
+		if v := policy.Violations(&GlobalSecret{}, "kubernetes.io/tls", nil); len(v) != 0 {
+			t.Errorf("forbidden %q: expected no violations, got %q", forbidden, v)
+		}
+		if v := policy.Violations(&GlobalConfig{}, "", nil); len(v) != 0 {
+			t.Errorf("forbidden %q: expected no violations of a globalconfig, got %q", forbidden, v)
+		}
+	}
+}
@@ -31,13 +31,54 @@ type GlobalSecretSpec struct {
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	Namespaces NamespacesRegex `json:"namespaces"`
 
+	// the type of the replicated secrets, defaults to Opaque
+	// +kubebuilder:default=Opaque
 	// +kubebuilder:validation:Enum={"Opaque","kubernetes.io/service-account-token","kubernetes.io/dockercfg","kubernetes.io/dockerconfigjson","kubernetes.io/basic-auth","kubernetes.io/ssh-auth","kubernetes.io/tls","bootstrap.kubernetes.io/token"}
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	// +kubebuilder:printcolumn:JSONPath=".spec.type",name="Type",type="string"
+	// +optional
 	Type string `json:"type"`
 
+	// the base64 encoded data of the replicated secrets
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
-	Data map[string]string `json:"data"`
+	Data SecretData `json:"data"`
+
+	// restart the Deployments, StatefulSets and DaemonSets, which consume the
+	// replicated secret, whenever the data changes
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Rollout bool `json:"rollout,omitempty"`
+
+	// stop the replication, while suspended, the existing secrets are neither
+	// created, updated nor removed, but the drift is still reported in the status
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	Suspend bool `json:"suspend,omitempty"`
+
+	// only calculate the changes to the secrets and report them in status.plan,
+	// without creating, updating or removing any secrets
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// update the outdated secrets in waves and batches, instead of
+	// updating all namespaces at once
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	RolloutStrategy *RolloutStrategy `json:"rolloutStrategy,omitempty"`
+
+	// the number of former revisions of the data, which are kept for a rollback,
+	// defaults to 10, 0 disables the history
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// the interval, in which the secrets are checked again without an event, overrides
+	// the interval of the operator, 0 disables the periodic check
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	ResyncInterval *metav1.Duration `json:"resyncInterval,omitempty"`
 }
 
 // GlobalSecretStatus defines the observed state of GlobalSecret
@@ -48,11 +89,35 @@ type GlobalSecretStatus struct {
 	// +operator-sdk:csv:customresourcedefinitions:type=status
 	DeployedSecrets []DeployedSecret `json:"deployedsecrets,omitempty"`
 
+	// the number of the revision, which contains the current data
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	CurrentRevision int64 `json:"currentRevision,omitempty"`
+
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Rollout *RolloutStatus `json:"rollout,omitempty"`
+
+	// the changes, which would be applied, if the dry run was disabled
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=status
+	Plan *ReplicationPlan `json:"plan,omitempty"`
+
 	// +operator-sdk:csv:customresourcedefinitions:type=status
 	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type" protobuf:"bytes,1,rep,name=conditions"`
 }
 
-type DeployedSecret struct{}
+// the state of a replicated secret in one of the matching namespaces
+type DeployedSecret struct {
+	Namespace string `json:"namespace"`
+
+	// the content hash of the existing secret, empty if it does not exist
+	// +optional
+	ContentHash string `json:"contenthash,omitempty"`
+
+	// whether the secret exists and contains the current data
+	InSync bool `json:"insync"`
+}
 
 // GlobalSecret is the Schema for the globalsecrets API
 // +kubebuilder:subresource:status
@@ -0,0 +1,91 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// SetupWebhookWithManager registers the defaulting and the validating webhook of the globalsecrets
+func (r *GlobalSecret) SetupWebhookWithManager(mgr ctrl.Manager, v *GlobalValidator) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithDefaulter(&globalSecretDefaulter{}).
+		WithValidator(&globalSecretValidator{v}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/mutate-globals-jnnkrdb-de-v1beta2-globalsecret,mutating=true,failurePolicy=fail,sideEffects=None,groups=globals.jnnkrdb.de,resources=globalsecrets,verbs=create;update,versions=v1beta2,name=mglobalsecret.kb.io,admissionReviewVersions=v1
+
+type globalSecretDefaulter struct{}
+
+var _ webhook.CustomDefaulter = &globalSecretDefaulter{}
+
+// Default implements webhook.CustomDefaulter
+func (d *globalSecretDefaulter) Default(ctx context.Context, obj runtime.Object) error {
+	gs, ok := obj.(*GlobalSecret)
+	if !ok {
+		return fmt.Errorf("expected a GlobalSecret, got %T", obj)
+	}
+	gs.Spec.Namespaces.Default()
+	if gs.Spec.Type == "" {
+		gs.Spec.Type = DefaultSecretType
+	}
+	if gs.Spec.RolloutStrategy != nil {
+		gs.Spec.RolloutStrategy.Default()
+	}
+	if gs.Spec.RevisionHistoryLimit == nil {
+		var limit = DefaultRevisionHistoryLimit
+		gs.Spec.RevisionHistoryLimit = &limit
+	}
+	return nil
+}
+
+//+kubebuilder:webhook:path=/validate-globals-jnnkrdb-de-v1beta2-globalsecret,mutating=false,failurePolicy=fail,sideEffects=None,groups=globals.jnnkrdb.de,resources=globalsecrets,verbs=create;update,versions=v1beta2,name=vglobalsecret.kb.io,admissionReviewVersions=v1
+
+type globalSecretValidator struct {
+	*GlobalValidator
+}
+
+var _ webhook.CustomValidator = &globalSecretValidator{}
+
+// ValidateCreate implements webhook.CustomValidator
+func (v *globalSecretValidator) ValidateCreate(ctx context.Context, obj runtime.Object) error {
+	gs, ok := obj.(*GlobalSecret)
+	if !ok {
+		return fmt.Errorf("expected a GlobalSecret, got %T", obj)
+	}
+	if err := validateRolloutStrategy(gs.Spec.RolloutStrategy); err != nil {
+		return err
+	}
+	return v.validateTargetNamespaces(ctx, gs, gs.Spec.Namespaces, "secrets", gs.Spec.Rollout)
+}
+
+// ValidateUpdate implements webhook.CustomValidator
+func (v *globalSecretValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) error {
+	return v.ValidateCreate(ctx, newObj)
+}
+
+// ValidateDelete implements webhook.CustomValidator
+func (v *globalSecretValidator) ValidateDelete(ctx context.Context, obj runtime.Object) error {
+	return nil
+}
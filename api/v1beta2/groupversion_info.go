@@ -20,6 +20,15 @@ limitations under the License.
 package v1beta2
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"sort"
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -40,10 +49,156 @@ var (
 // set the finalizer objects
 const FinalizerGlobal string = "globals.jnnkrdb.de/v1beta2.finalizer"
 
+// the labels, which are set on every replicated configmap and secret
+const (
+	// the api version of the global object
+	LabelVersion string = "globals.jnnkrdb.de/confrdb.version"
+
+	// the uid of the global object
+	LabelUID string = "globals.jnnkrdb.de/confrdb.uid"
+)
+
 // get/set the labels, whehter to compare or to set
 func MatchingLables(uid types.UID) client.MatchingLabels {
 	return client.MatchingLabels{
-		"globals.jnnkrdb.de/confrdb.version": GroupVersion.Version,
-		"globals.jnnkrdb.de/confrdb.uid":     string(uid),
+		LabelVersion: GroupVersion.Version,
+		LabelUID:     string(uid),
+	}
+}
+
+// the labels and annotations, which point from a replicated configmap or secret to
+// its global object, the name is an annotation, since it can exceed the length of a label
+const (
+	// the kind of the global object, GlobalConfig or GlobalSecret
+	LabelParentKind string = "globals.jnnkrdb.de/parent-kind"
+
+	// the namespace of the global object
+	LabelParentNamespace string = "globals.jnnkrdb.de/parent-namespace"
+
+	// the name of the global object
+	AnnotationParentName string = "globals.jnnkrdb.de/parent-name"
+)
+
+// get the labels for a replicated object, which contain the labels of MatchingLables
+// and the kind and namespace of the global object
+func Labels(kind string, parent metav1.Object) map[string]string {
+	var labels = MatchingLables(parent.GetUID())
+	labels[LabelParentKind] = kind
+	labels[LabelParentNamespace] = parent.GetNamespace()
+	return labels
+}
+
+// get the kind and the key of the global object of a replicated object, ok is
+// false, if the replicated object does not point to a global object
+func ParentOf(obj metav1.Object) (kind string, key types.NamespacedName, ok bool) {
+	kind = obj.GetLabels()[LabelParentKind]
+	key = types.NamespacedName{
+		Namespace: obj.GetLabels()[LabelParentNamespace],
+		Name:      obj.GetAnnotations()[AnnotationParentName],
+	}
+	return kind, key, kind != "" && key.Namespace != "" && key.Name != ""
+}
+
+// annotations, which are set on every replicated configmap and secret
+const (
+	// the keyed content hash of the replicated data, see ContentHash
+	AnnotationContentHash string = "globals.jnnkrdb.de/content-hash"
+
+	// the generation of the global object, which provided the data
+	AnnotationSourceGeneration string = "globals.jnnkrdb.de/source-generation"
+)
+
+// annotations, which are used for the revision history of the global objects
+const (
+	// the number of a stored revision
+	AnnotationRevision string = "globals.jnnkrdb.de/revision"
+
+	// set this annotation on a global object, to roll back its data to the given revision number
+	AnnotationRollbackTo string = "globals.jnnkrdb.de/rollback-to"
+)
+
+// set this annotation to "true" on a global object, to replicate it into the
+// protected namespaces of the manager, see CompiledNamespacesRegex.Protect
+const AnnotationAllowProtectedNamespaces string = "globals.jnnkrdb.de/allow-protected-namespaces"
+
+// set this annotation to a number on a global object, to raise the verbosity of its
+// logs, e.g. "1" logs every step of its reconciliation, regardless of the log level
+// of the manager
+const AnnotationLogVerbosity string = "globals.jnnkrdb.de/log-verbosity"
+
+// the namespaces, which are protected by default, the manager adds its own namespace
+var DefaultProtectedNamespaces = []string{"kube-system", "kube-public", "kube-node-lease"}
+
+// check, whether a global object opted in to be replicated into the protected
+// namespaces, an invalid value of the annotation is an error
+func AllowsProtectedNamespaces(annotations map[string]string) (bool, error) {
+	value, ok := annotations[AnnotationAllowProtectedNamespaces]
+	if !ok {
+		return false, nil
+	}
+	allow, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid value %q of the annotation %s: %w", value, AnnotationAllowProtectedNamespaces, err)
+	}
+	return allow, nil
+}
+
+// get the verbosity, which a global object requested with the annotation
+// [AnnotationLogVerbosity], an invalid value of the annotation is an error
+func LogVerbosity(annotations map[string]string) (int, error) {
+	value, ok := annotations[AnnotationLogVerbosity]
+	if !ok {
+		return 0, nil
+	}
+	verbosity, err := strconv.Atoi(value)
+	if err != nil || verbosity < 0 {
+		return 0, fmt.Errorf("invalid value %q of the annotation %s: must be a non-negative number", value, AnnotationLogVerbosity)
+	}
+	return verbosity, nil
+}
+
+// get the annotations for a replicated object
+func Annotations(hash string, parent metav1.Object) map[string]string {
+	return map[string]string{
+		AnnotationContentHash:      hash,
+		AnnotationSourceGeneration: strconv.FormatInt(parent.GetGeneration(), 10),
+		AnnotationParentName:       parent.GetName(),
+	}
+}
+
+// calculate a stable hash over the given data, the keys are sorted, so the
+// order of the map does not change the result
+//
+// the hash is an hmac with the key of the operator, since it is annotated on the
+// replicated objects and the pod templates, which are readable by more users than
+// the data, so short values of a secret can not be guessed from the hash
+func ContentHash(key []byte, data map[string]string) string {
+	var h = hmac.New(sha256.New, key)
+	writeData(h, data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// calculate the unkeyed hash of the data, it must only be stored next to the data
+// itself, e.g. in the revisions, the replicated objects of former versions carry it
+// in their annotation
+func UnkeyedContentHash(data map[string]string) string {
+	var h = sha256.New()
+	writeData(h, data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// write the data to a hash in the order of its keys
+func writeData(h hash.Hash, data map[string]string) {
+
+	var keys = make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		// write the length of each part first, so that different
+		// combinations of keys and values can not collide
+		h.Write([]byte(fmt.Sprintf("%d:%s%d:%s", len(k), k, len(data[k]), data[k])))
 	}
 }
@@ -0,0 +1,79 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestParentOf(t *testing.T) {
+	var gs = &GlobalSecret{}
+	gs.Name, gs.Namespace, gs.UID, gs.Generation = "pull-secret", "platform", "gs-uid", 3
+
+	var secret = &v1.Secret{}
+	secret.Labels = Labels("GlobalSecret", gs)
+	secret.Annotations = Annotations(ContentHash(nil, nil), gs)
+
+	kind, key, ok := ParentOf(secret)
+	if !ok || kind != "GlobalSecret" || key.Namespace != "platform" || key.Name != "pull-secret" {
+		t.Errorf("unexpected parent %s %s (%t)", kind, key, ok)
+	}
+	if secret.Labels[LabelUID] != "gs-uid" || secret.Annotations[AnnotationSourceGeneration] != "3" {
+		t.Errorf("unexpected metadata %v %v", secret.Labels, secret.Annotations)
+	}
+
+	// replicated objects of former versions only carry the labels of MatchingLables
+	secret.Labels = MatchingLables(gs.UID)
+	if _, _, ok = ParentOf(secret); ok {
+		t.Error("expected no parent without the parent labels")
+	}
+}
+
+func TestLogVerbosity(t *testing.T) {
+	for value, want := range map[string]int{"": 0, "2": 2} {
+		var annotations = map[string]string{}
+		if value != "" {
+			annotations[AnnotationLogVerbosity] = value
+		}
+		if verbosity, err := LogVerbosity(annotations); err != nil || verbosity != want {
+			t.Errorf("%q: expected %d, got %d (%v)", value, want, verbosity, err)
+		}
+	}
+	for _, value := range []string{"debug", "-1"} {
+		if _, err := LogVerbosity(map[string]string{AnnotationLogVerbosity: value}); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	var data = map[string]string{"pin": "1234"}
+	var key, other = []byte("0123456789abcdef0123456789abcdef"), []byte("fedcba9876543210fedcba9876543210")
+
+	// the hash is stable, but can not be calculated without the key
+	if ContentHash(key, data) != ContentHash(key, map[string]string{"pin": "1234"}) {
+		t.Error("expected a stable hash")
+	}
+	if ContentHash(key, data) == ContentHash(other, data) || ContentHash(key, data) == UnkeyedContentHash(data) {
+		t.Error("expected the hash to depend on the key")
+	}
+	if ContentHash(key, map[string]string{"a": "bc"}) == ContentHash(key, map[string]string{"ab": "c"}) {
+		t.Error("expected different data not to collide")
+	}
+}
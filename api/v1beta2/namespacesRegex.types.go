@@ -2,111 +2,402 @@ package v1beta2
 
 import (
 	"context"
+	"fmt"
 	"regexp"
+	"regexp/syntax"
+	"sort"
+	"strings"
 
 	"github.com/go-logr/logr"
 	v1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+// the way the patterns of a NamespacesRegex are compared with the names of the namespaces
+// +kubebuilder:validation:Enum=Regex;AnchoredRegex;Glob;Exact
+type MatchMode string
+
+const (
+	// the patterns are regexpressions, which match substrings of the names,
+	// e.g. "prod" matches "prod", "preprod" and "product-x"
+	MatchModeRegex MatchMode = "Regex"
+
+	// the patterns are regexpressions, which have to match the whole names
+	MatchModeAnchoredRegex MatchMode = "AnchoredRegex"
+
+	// the patterns are globs, "*" matches any sequence of characters and
+	// "?" matches a single character, e.g. "team-*"
+	MatchModeGlob MatchMode = "Glob"
+
+	// the patterns are the exact names of the namespaces
+	MatchModeExact MatchMode = "Exact"
+)
+
 // struct which contains the information about the namespace regex
 type NamespacesRegex struct {
 
-	// +kubebuilder:default={default}
+	// the way the patterns of both lists are compared with the names of the namespaces,
+	// defaults to Regex
+	// +kubebuilder:default=Regex
+	// +optional
+	// +operator-sdk:csv:customresourcedefinitions:type=spec
+	MatchMode MatchMode `json:"matchMode,omitempty"`
+
+	// the patterns of the namespaces, which are never targeted, even if they match
+	// the list matchregex, defaults to an empty list
+	// +optional
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	AvoidRegex []string `json:"avoidregex"`
 
-	// +kubebuilder:default={default}
+	// the patterns of the namespaces, which are targeted, defaults to an empty
+	// list, which targets no namespace
+	// +optional
 	// +operator-sdk:csv:customresourcedefinitions:type=spec
 	MatchRegex []string `json:"matchregex"`
 }
 
+// the compiled regexpressions of a NamespacesRegex, the expressions are compiled
+// once and can be reused for every namespace
+// +kubebuilder:object:generate=false
+type CompiledNamespacesRegex struct {
+	avoid     []*regexp.Regexp
+	match     []*regexp.Regexp
+	protected map[string]bool
+
+	// the only namespaces, which can be matched, nil allows all namespaces
+	allowed map[string]bool
+}
+
+// compile the regexpressions of both lists
+//
+// an invalid regexpression is returned as error, instead of being skipped
+func (nsr NamespacesRegex) Compile() (*CompiledNamespacesRegex, error) {
+
+	var cnsr = &CompiledNamespacesRegex{
+		avoid: make([]*regexp.Regexp, 0, len(nsr.AvoidRegex)),
+		match: make([]*regexp.Regexp, 0, len(nsr.MatchRegex)),
+	}
+
+	for i := range nsr.AvoidRegex {
+		re, err := nsr.MatchMode.compile(nsr.AvoidRegex[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid avoidregex %q: %w", nsr.AvoidRegex[i], err)
+		}
+		cnsr.avoid = append(cnsr.avoid, re)
+	}
+
+	for i := range nsr.MatchRegex {
+		re, err := nsr.MatchMode.compile(nsr.MatchRegex[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid matchregex %q: %w", nsr.MatchRegex[i], err)
+		}
+		cnsr.match = append(cnsr.match, re)
+	}
+
+	return cnsr, nil
+}
+
+// compile a pattern into a regexpression, depending on the match mode
+func (mm MatchMode) compile(pattern string) (*regexp.Regexp, error) {
+	switch mm {
+	case MatchModeRegex, "":
+		return regexp.Compile(pattern)
+
+	case MatchModeAnchoredRegex:
+		return compileAnchored(pattern)
+
+	case MatchModeGlob:
+		var expr strings.Builder
+		expr.WriteString("^")
+		for _, r := range pattern {
+			switch r {
+			case '*':
+				expr.WriteString(".*")
+			case '?':
+				expr.WriteString(".")
+			default:
+				expr.WriteString(regexp.QuoteMeta(string(r)))
+			}
+		}
+		expr.WriteString("$")
+		return regexp.Compile(expr.String())
+
+	case MatchModeExact:
+		return regexp.Compile("^" + regexp.QuoteMeta(pattern) + "$")
+	}
+	return nil, fmt.Errorf("unknown match mode %q", mm)
+}
+
+// compile a regexpression, which has to match the whole names
+//
+// the pattern is compiled on its own first, so it can not close the group around it,
+// e.g. "a)|(?:.*" would match every name after "^(?:" and ")$" were added, the
+// parsed anchored regexpression must also consist of the anchors and the pattern
+func compileAnchored(pattern string) (*regexp.Regexp, error) {
+	if _, err := syntax.Parse(pattern, syntax.Perl); err != nil {
+		return nil, err
+	}
+
+	var anchored = "^(?:" + pattern + ")$"
+	re, err := syntax.Parse(anchored, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	if re.Op != syntax.OpConcat || len(re.Sub) < 2 || re.Sub[0].Op != syntax.OpBeginText || re.Sub[len(re.Sub)-1].Op != syntax.OpEndText {
+		return nil, fmt.Errorf("the pattern %q can not be anchored", pattern)
+	}
+	return regexp.Compile(anchored)
+}
+
+// get two lists of namespaces, see CompiledNamespacesRegex.CalculateNamespaces
+//
+// the regexpressions are compiled on every call, use Compile to reuse them
+func (nsr NamespacesRegex) CalculateNamespaces(l logr.Logger, ctx context.Context, c client.Client) (mustMatch, mustAvoid []v1.Namespace, err error) {
+
+	l.V(1).Info("calculating namespaces for the following lists", "NamespacesRegex", nsr)
+
+	var cnsr *CompiledNamespacesRegex
+	if cnsr, err = nsr.Compile(); err != nil {
+		l.Error(err, "error compiling the regexpressions")
+		return
+	}
+	return cnsr.CalculateNamespaces(l, ctx, c)
+}
+
 // get two lists of namespaces
 //
 // the 1. list contains all namespaces
 //
 // the 2. list, contains all namespaces, which match with the
 // list of regexpressions from the matches-array, without the namespaces,
-// which match with the avoid-array
-func (nsr NamespacesRegex) CalculateNamespaces(l logr.Logger, ctx context.Context, c client.Client) (mustMatch, mustAvoid []v1.Namespace, err error) {
+// which match with the avoid-array or are protected
+//
+// the namespaces, which are excluded by Restrict, are in neither list, so they
+// are never touched
+func (cnsr *CompiledNamespacesRegex) CalculateNamespaces(l logr.Logger, ctx context.Context, c client.Client) (mustMatch, mustAvoid []v1.Namespace, err error) {
 
 	var namespaceList = &v1.NamespaceList{}
 
-	l.Info("calculating namespaces for the following lists", "NamespacesRegex", nsr)
-
 	if err = c.List(ctx, namespaceList, &client.ListOptions{}); err == nil {
 
 		// parse through all registered namespaces
 		for i := range namespaceList.Items {
 
-			// check, if the namespace has to be avoided during deployment
-			var inList bool = false
-			if inList, err = stringMatchesRegExpList(namespaceList.Items[i].Name, nsr.AvoidRegex); err != nil {
-
-				l.Error(err, "error calculating avoids", "current namespace", namespaceList.Items[i].Name, "AvoidRegex", nsr.AvoidRegex)
+			if cnsr.allowed != nil && !cnsr.allowed[namespaceList.Items[i].Name] {
+				continue
+			}
 
-				return
+			if cnsr.Matches(namespaceList.Items[i].Name) {
+				// if the namespace is in the list [MatchRegex] and not in the list [AvoidRegex], then
+				// append the namespace to the namespaces [mustMatch]
+				mustMatch = append(mustMatch, namespaceList.Items[i])
 
 			} else {
-				if inList {
-					// if the namespace is in the list [AvoidRegex], append the namespace to the list [mustAvoid]
-					// and calculate the next namespace
-					mustAvoid = append(mustAvoid, namespaceList.Items[i])
-					continue
-				}
+				// if the namespace is in the list [AvoidRegex] or not in the list [MatchRegex], then
+				// append the namespace to the namespaces [mustAvoid]
+				mustAvoid = append(mustAvoid, namespaceList.Items[i])
 			}
+		}
+	}
+	return
+}
 
-			// if the namespace is not in the list [AvoidRegex], check if the namespace is in the list [MatchRegex]
-			if inList, err = stringMatchesRegExpList(namespaceList.Items[i].Name, nsr.MatchRegex); err != nil {
+// get a copy of the compiled regexpressions, which always avoids the given namespaces,
+// no matter what the lists [MatchRegex] and [AvoidRegex] contain
+//
+// the compiled regexpressions are shared with the copy, so they are not compiled again
+func (cnsr *CompiledNamespacesRegex) Protect(namespaces []string) *CompiledNamespacesRegex {
 
-				l.Error(err, "error calculating matches", "current namespace", namespaceList.Items[i].Name, "MatchRegex", nsr.MatchRegex)
+	var protected = &CompiledNamespacesRegex{
+		avoid:     cnsr.avoid,
+		match:     cnsr.match,
+		protected: make(map[string]bool, len(cnsr.protected)+len(namespaces)),
+		allowed:   cnsr.allowed,
+	}
+	for ns := range cnsr.protected {
+		protected.protected[ns] = true
+	}
+	for _, ns := range namespaces {
+		protected.protected[ns] = true
+	}
+	return protected
+}
 
-				return
+// get a copy of the compiled regexpressions, which can only match the given namespaces,
+// an empty list of namespaces does not restrict the copy
+//
+// the compiled regexpressions are shared with the copy, so they are not compiled again
+func (cnsr *CompiledNamespacesRegex) Restrict(namespaces []string) *CompiledNamespacesRegex {
 
-			} else {
+	var restricted = &CompiledNamespacesRegex{
+		avoid:     cnsr.avoid,
+		match:     cnsr.match,
+		protected: cnsr.protected,
+	}
+	if len(namespaces) == 0 {
+		restricted.allowed = cnsr.allowed
+		return restricted
+	}
+	restricted.allowed = make(map[string]bool, len(namespaces))
+	for _, ns := range namespaces {
+		if cnsr.allowed == nil || cnsr.allowed[ns] {
+			restricted.allowed[ns] = true
+		}
+	}
+	return restricted
+}
 
-				if inList {
-					// if the namespace is in the list [MatchRegex], then append the namespace to
-					// the namespaces [mustMatch]
-					mustMatch = append(mustMatch, namespaceList.Items[i])
+// check, whether a namespace must be matched, which means, that the namespace
+// is allowed and not protected, matches the list [MatchRegex] and does not match
+// the list [AvoidRegex]
+func (cnsr *CompiledNamespacesRegex) Matches(namespace string) bool {
+	if cnsr.allowed != nil && !cnsr.allowed[namespace] {
+		return false
+	}
+	return !cnsr.protected[namespace] && !matchesAny(namespace, cnsr.avoid) && matchesAny(namespace, cnsr.match)
+}
 
-				} else {
-					// if the namespace also is not in the list [MatchRegex], then append the namespace to
-					// the namespaces [mustAvoid]
-					mustAvoid = append(mustAvoid, namespaceList.Items[i])
-				}
+// check whether a string matches a list of compiled regexpressions or not
+func matchesAny(comp string, regexpList []*regexp.Regexp) bool {
+	for i := range regexpList {
+		if regexpList[i].MatchString(comp) {
+			return true
+		}
+	}
+	return false
+}
+
+// the maximum number of names, which are enumerated by Names
+const maxEnumeratedNames = 256
+
+// get the names of all namespaces, which can ever be matched, whether they exist or not,
+// ok is false, if a pattern of the list [MatchRegex] can match an unbounded number of
+// names, e.g. "^team-" or "prod", which would also match the namespaces, which are
+// created later
+//
+// a pattern is bounded, if it is anchored at both ends and contains no repetitions,
+// e.g. "^team-(a|b)-dev$", the patterns of the match modes Exact and Glob without
+// wildcards are always bounded
+func (cnsr *CompiledNamespacesRegex) Names() (names []string, ok bool) {
+
+	var set = make(map[string]bool)
+	for _, re := range cnsr.match {
+		tree, err := syntax.Parse(re.String(), syntax.Perl)
+		if err != nil {
+			return nil, false
+		}
+		tree = tree.Simplify()
+		if !anchored(tree, syntax.OpBeginText, true) || !anchored(tree, syntax.OpEndText, false) {
+			return nil, false
+		}
+
+		var strs []string
+		if strs, ok = enumerate(tree); !ok {
+			return nil, false
+		}
+		for _, s := range strs {
+			if set[s] = true; len(set) > maxEnumeratedNames {
+				return nil, false
 			}
 		}
 	}
-	return
+
+	for name := range set {
+		if cnsr.Matches(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, true
+}
+
+// check whether every path through a regexpression starts or ends with the anchor
+func anchored(re *syntax.Regexp, anchor syntax.Op, start bool) bool {
+	switch re.Op {
+	case anchor:
+		return true
+	case syntax.OpCapture:
+		return anchored(re.Sub[0], anchor, start)
+	case syntax.OpConcat:
+		if len(re.Sub) == 0 {
+			return false
+		}
+		if start {
+			return anchored(re.Sub[0], anchor, start)
+		}
+		return anchored(re.Sub[len(re.Sub)-1], anchor, start)
+	case syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			if !anchored(sub, anchor, start) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
 }
 
-// check whether a string exists in a list of regexpressions or not
-func stringMatchesRegExpList(comp string, regexpList []string) (bool, error) {
+// get all strings, which are matched by a simplified regexpression, the anchors
+// match the empty string, ok is false, if the strings can not be enumerated
+func enumerate(re *syntax.Regexp) (strs []string, ok bool) {
+	switch re.Op {
+	case syntax.OpEmptyMatch, syntax.OpBeginText, syntax.OpEndText:
+		return []string{""}, true
 
-	for i := range regexpList {
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase != 0 {
+			return nil, false
+		}
+		return []string{string(re.Rune)}, true
 
-		if matched, err := regexp.MatchString(regexpList[i], comp); err != nil {
+	case syntax.OpCharClass:
+		for i := 0; i+1 < len(re.Rune); i += 2 {
+			if len(strs)+int(re.Rune[i+1]-re.Rune[i]) >= maxEnumeratedNames {
+				return nil, false
+			}
+			for r := re.Rune[i]; r <= re.Rune[i+1]; r++ {
+				strs = append(strs, string(r))
+			}
+		}
+		return strs, true
 
-			return false, nil
+	case syntax.OpCapture:
+		return enumerate(re.Sub[0])
 
-		} else {
+	case syntax.OpQuest:
+		if strs, ok = enumerate(re.Sub[0]); !ok {
+			return nil, false
+		}
+		return append(strs, ""), true
 
-			if matched {
+	case syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			var subStrs []string
+			if subStrs, ok = enumerate(sub); !ok || len(strs)+len(subStrs) > maxEnumeratedNames {
+				return nil, false
+			}
+			strs = append(strs, subStrs...)
+		}
+		return strs, true
 
-				return true, nil
+	case syntax.OpConcat:
+		strs = []string{""}
+		for _, sub := range re.Sub {
+			var subStrs []string
+			if subStrs, ok = enumerate(sub); !ok || len(strs)*len(subStrs) > maxEnumeratedNames {
+				return nil, false
+			}
+			var product = make([]string, 0, len(strs)*len(subStrs))
+			for _, prefix := range strs {
+				for _, suffix := range subStrs {
+					product = append(product, prefix+suffix)
+				}
 			}
+			strs = product
 		}
+		return strs, true
 	}
-	return false, nil
-}
 
-// find a string in a list of string
-//func stringInList(comp string, list []string) bool {
-//	for i := range list {
-//		if list[i] == comp {
-//			return true
-//		}
-//	}
-//	return false
-//}
+	// repetitions and the classes of any character can not be enumerated
+	return nil, false
+}
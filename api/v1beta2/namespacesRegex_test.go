@@ -0,0 +1,296 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// the namespace regex, which is used in the benchmarks
+var benchmarkNamespacesRegex = NamespacesRegex{
+	AvoidRegex: []string{"^kube-", "-prod$", "^team-(a|b)-legacy"},
+	MatchRegex: []string{"-dev$", "-staging$", "^team-[a-f]-", "internal", "^shared$"},
+}
+
+// create a fake client, which contains the given number of namespaces
+func newNamespaceClient(tb testing.TB, count int) client.Client {
+	tb.Helper()
+
+	var objs = make([]client.Object, 0, count)
+	for i := 0; i < count; i++ {
+		var ns = &v1.Namespace{}
+		ns.Name = fmt.Sprintf("team-%c-%d-%s", 'a'+i%10, i, []string{"dev", "staging", "prod", "internal"}[i%4])
+		objs = append(objs, ns)
+	}
+	return fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(objs...).Build()
+}
+
+func TestCompileInvalidRegex(t *testing.T) {
+	for _, nsr := range []NamespacesRegex{
+		{AvoidRegex: []string{"team-(a|b"}, MatchRegex: []string{"."}},
+		{AvoidRegex: []string{}, MatchRegex: []string{"[z-a]"}},
+	} {
+		if _, err := nsr.Compile(); err == nil {
+			t.Errorf("expected an error for %+v", nsr)
+		}
+	}
+}
+
+func TestCompileAnchoredInjection(t *testing.T) {
+	for _, pattern := range []string{"a)|(?:.*", "a)|^(?:.*", `\Q)|(?:.*`, "a$)|(.*"} {
+		re, err := compileAnchored(pattern)
+		if err == nil && re.MatchString("kube-system") {
+			t.Errorf("the pattern %q escaped the anchors: %s", pattern, re)
+		}
+	}
+	for _, pattern := range []string{"a)|(?:.*", "a)|^(?:.*"} {
+		if _, err := (NamespacesRegex{MatchMode: MatchModeAnchoredRegex, MatchRegex: []string{pattern}}).Compile(); err == nil {
+			t.Errorf("expected an error for %q", pattern)
+		}
+	}
+}
+
+func TestMatchModes(t *testing.T) {
+	for _, tc := range []struct {
+		mode      MatchMode
+		pattern   string
+		matches   []string
+		unmatched []string
+	}{
+		{MatchModeRegex, "prod", []string{"prod", "preprod", "product-x"}, []string{"dev"}},
+		{MatchModeAnchoredRegex, "prod", []string{"prod"}, []string{"preprod", "product-x"}},
+		{MatchModeAnchoredRegex, "team-(a|b)", []string{"team-a", "team-b"}, []string{"team-ab", "x-team-a"}},
+		{MatchModeGlob, "team-*", []string{"team-", "team-a", "team-a-dev"}, []string{"xteam-a", "team"}},
+		{MatchModeGlob, "team-?-dev", []string{"team-a-dev"}, []string{"team-ab-dev"}},
+		{MatchModeGlob, "a.b", []string{"a.b"}, []string{"axb"}},
+		{MatchModeExact, "prod", []string{"prod"}, []string{"preprod", "prod-1"}},
+		{MatchModeExact, "a.b", []string{"a.b"}, []string{"axb"}},
+	} {
+		cnsr, err := NamespacesRegex{MatchMode: tc.mode, MatchRegex: []string{tc.pattern}}.Compile()
+		if err != nil {
+			t.Fatalf("%s %q: %v", tc.mode, tc.pattern, err)
+		}
+		for _, name := range tc.matches {
+			if !cnsr.Matches(name) {
+				t.Errorf("%s %q must match %q", tc.mode, tc.pattern, name)
+			}
+		}
+		for _, name := range tc.unmatched {
+			if cnsr.Matches(name) {
+				t.Errorf("%s %q must not match %q", tc.mode, tc.pattern, name)
+			}
+		}
+	}
+
+	if _, err := (NamespacesRegex{MatchMode: "Fuzzy", MatchRegex: []string{"a"}}).Compile(); err == nil {
+		t.Error("expected an error for an unknown match mode")
+	}
+}
+
+func TestCalculateNamespaces(t *testing.T) {
+	var c = newNamespaceClient(t, 40)
+
+	matches, avoids, err := benchmarkNamespacesRegex.CalculateNamespaces(logr.Discard(), context.Background(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches)+len(avoids) != 40 {
+		t.Fatalf("expected 40 namespaces, got %d", len(matches)+len(avoids))
+	}
+
+	var prod = regexp.MustCompile("-prod$")
+	for _, ns := range matches {
+		if prod.MatchString(ns.Name) {
+			t.Errorf("namespace %s must be avoided", ns.Name)
+		}
+	}
+}
+
+// the regexpressions are compiled on every call
+func BenchmarkCalculateNamespaces(b *testing.B) {
+	var c = newNamespaceClient(b, 2000)
+	var ctx = context.Background()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := benchmarkNamespacesRegex.CalculateNamespaces(logr.Discard(), ctx, c); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// the regexpressions are compiled once, like the controllers do per generation
+func BenchmarkCompiledCalculateNamespaces(b *testing.B) {
+	var c = newNamespaceClient(b, 2000)
+	var ctx = context.Background()
+
+	cnsr, err := benchmarkNamespacesRegex.Compile()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, _, err := cnsr.CalculateNamespaces(logr.Discard(), ctx, c); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// compare the matching of 2000 namespaces with and without compiled regexpressions,
+// without the costs of listing the namespaces
+func BenchmarkMatchNamespaces(b *testing.B) {
+	var names = make([]string, 2000)
+	for i := range names {
+		names[i] = fmt.Sprintf("team-%c-%d-dev", 'a'+i%10, i)
+	}
+
+	b.Run("MatchString", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, name := range names {
+				for _, expr := range append(benchmarkNamespacesRegex.AvoidRegex, benchmarkNamespacesRegex.MatchRegex...) {
+					if _, err := regexp.MatchString(expr, name); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		}
+	})
+
+	b.Run("Compiled", func(b *testing.B) {
+		cnsr, err := benchmarkNamespacesRegex.Compile()
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for _, name := range names {
+				cnsr.Matches(name)
+			}
+		}
+	})
+}
+
+func TestProtect(t *testing.T) {
+	cnsr, err := NamespacesRegex{MatchRegex: []string{"."}}.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var protected = cnsr.Protect(DefaultProtectedNamespaces).Protect([]string{"confrdb"})
+	for _, ns := range append(DefaultProtectedNamespaces, "confrdb") {
+		if protected.Matches(ns) {
+			t.Errorf("protected namespace %s must not match", ns)
+		}
+		if !cnsr.Matches(ns) {
+			t.Errorf("the unprotected regex must still match %s", ns)
+		}
+	}
+	if !protected.Matches("default") {
+		t.Error("namespace default must match")
+	}
+}
+
+func TestRestrict(t *testing.T) {
+	cnsr, err := NamespacesRegex{MatchRegex: []string{"^team-"}, AvoidRegex: []string{"-prod$"}}.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var restricted = cnsr.Restrict([]string{"team-a", "team-a-prod", "default"}).Protect([]string{"team-a"})
+	for ns, want := range map[string]bool{
+		"team-a":      false, // protected
+		"team-a-prod": false, // avoided
+		"default":     false, // not matched
+		"team-b":      false, // not allowed
+	} {
+		if got := restricted.Matches(ns); got != want {
+			t.Errorf("Matches(%s) = %v, want %v", ns, got, want)
+		}
+	}
+	if !cnsr.Restrict([]string{"team-b"}).Matches("team-b") {
+		t.Error("namespace team-b must match")
+	}
+	if !cnsr.Restrict(nil).Matches("team-c") {
+		t.Error("an empty list must not restrict the namespaces")
+	}
+	if cnsr.Restrict([]string{"team-b"}).Restrict([]string{"team-c"}).Matches("team-c") {
+		t.Error("a restriction must not widen a former restriction")
+	}
+	// the namespaces outside of the restriction are neither matched nor avoided
+	matches, avoids, err := cnsr.Restrict([]string{"team-a-0-dev", "team-c-2-prod", "missing"}).
+		CalculateNamespaces(logr.Discard(), context.Background(), newNamespaceClient(t, 40))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].Name != "team-a-0-dev" || len(avoids) != 1 || avoids[0].Name != "team-c-2-prod" {
+		t.Errorf("expected team-a-0-dev to match and team-c-2-prod to be avoided, got %v and %v", matches, avoids)
+	}
+}
+
+func TestNames(t *testing.T) {
+	for _, tc := range []struct {
+		mode     MatchMode
+		patterns []string
+		names    []string
+		ok       bool
+	}{
+		{MatchModeRegex, []string{"^team-a-dev$"}, []string{"team-a-dev"}, true},
+		{MatchModeRegex, []string{"^team-(a|b)-dev$", "^shared$"}, []string{"shared", "team-a-dev", "team-b-dev"}, true},
+		{MatchModeRegex, []string{"^team-a-dev$|^team-[bc]-prod$"}, []string{"team-a-dev", "team-b-prod", "team-c-prod"}, true},
+		{MatchModeRegex, []string{"^team-a(-dev)?$"}, []string{"team-a", "team-a-dev"}, true},
+		{MatchModeRegex, []string{"team-a-dev"}, nil, false},
+		{MatchModeRegex, []string{"^team-a-dev"}, nil, false},
+		{MatchModeRegex, []string{"^team-a-dev$|prod"}, nil, false},
+		{MatchModeRegex, []string{"^team-.$"}, nil, false},
+		{MatchModeRegex, []string{"^team-a+$"}, nil, false},
+		{MatchModeRegex, []string{"(?i)^team$"}, nil, false},
+		{MatchModeRegex, []string{"^[a-z]{2}$"}, nil, false},
+		{MatchModeAnchoredRegex, []string{"team-(a|b)"}, []string{"team-a", "team-b"}, true},
+		{MatchModeAnchoredRegex, []string{"team-.*"}, nil, false},
+		{MatchModeGlob, []string{"a.b"}, []string{"a.b"}, true},
+		{MatchModeGlob, []string{"team-*"}, nil, false},
+		{MatchModeExact, []string{"prod", "a.b"}, []string{"a.b", "prod"}, true},
+	} {
+		cnsr, err := NamespacesRegex{MatchMode: tc.mode, MatchRegex: tc.patterns}.Compile()
+		if err != nil {
+			t.Fatalf("%s %q: %v", tc.mode, tc.patterns, err)
+		}
+		names, ok := cnsr.Names()
+		if ok != tc.ok || fmt.Sprint(names) != fmt.Sprint(tc.names) {
+			t.Errorf("%s %q: expected %q and %v, got %q and %v", tc.mode, tc.patterns, tc.names, tc.ok, names, ok)
+		}
+	}
+
+	// the avoided and protected namespaces are never returned
+	cnsr, err := NamespacesRegex{MatchRegex: []string{"^(kube-system|team-a-dev|team-a-prod)$"}, AvoidRegex: []string{"-prod$"}}.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names, ok := cnsr.Protect(DefaultProtectedNamespaces).Names(); !ok || fmt.Sprint(names) != "[team-a-dev]" {
+		t.Errorf("expected only team-a-dev, got %q and %v", names, ok)
+	}
+}
@@ -0,0 +1,33 @@
+package v1beta2
+
+// the changes, which a global object would apply to its replicated objects,
+// it is reported in the status of a global object in dry run mode
+type ReplicationPlan struct {
+
+	// the content hash of the data, which would be replicated
+	// +optional
+	ContentHash string `json:"contentHash,omitempty"`
+
+	// the number of namespaces, which are matched by the namespace regexpressions
+	Matched int32 `json:"matched"`
+
+	// the number of namespaces, which are avoided by the namespace regexpressions
+	Avoided int32 `json:"avoided"`
+
+	// the namespaces, in which the replicated object would be created
+	// +optional
+	Create []string `json:"create,omitempty"`
+
+	// the namespaces, in which the replicated object would be updated
+	// +optional
+	Update []string `json:"update,omitempty"`
+
+	// the namespaces, from which the replicated object would be removed
+	// +optional
+	Delete []string `json:"delete,omitempty"`
+
+	// the matching namespaces, which already contain an object with the same name,
+	// which is not owned by the global object, these objects are never touched
+	// +optional
+	Conflicts []string `json:"conflicts,omitempty"`
+}
@@ -0,0 +1,60 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+)
+
+func TestSecretDataRedacted(t *testing.T) {
+	const value = "c2VjcmV0LXBhc3N3b3Jk"
+
+	var gs = &GlobalSecret{}
+	gs.Name, gs.Namespace = "gs", "default"
+	gs.Annotations = map[string]string{annotationLastAppliedConfiguration: `{"spec":{"data":{"password":"` + value + `"}}}`}
+	gs.Spec.Data = SecretData{"password": value}
+
+	var logged []string
+	var l = funcr.New(func(prefix, args string) { logged = append(logged, args) }, funcr.Options{})
+	l.Info("reconciling", "GlobalSecret", gs, "spec", gs.Spec, "data", gs.Spec.Data)
+
+	for _, out := range append(logged,
+		fmt.Sprintf("%v", gs), fmt.Sprintf("%+v", gs), fmt.Sprintf("%+v", gs.Spec), fmt.Sprintf("%#v", gs.Spec),
+		fmt.Sprint(gs.Spec.Data), fmt.Errorf("error creating %v: %w", gs, fmt.Errorf("wrapped")).Error(),
+	) {
+		if strings.Contains(out, value) {
+			t.Errorf("the value was not redacted: %s", out)
+		}
+		if !strings.Contains(out, "password") {
+			t.Errorf("expected the key to stay visible: %s", out)
+		}
+	}
+
+	// the object itself and its json encoding are unchanged
+	if gs.Spec.Data["password"] != value || !strings.Contains(gs.Annotations[annotationLastAppliedConfiguration], value) {
+		t.Error("expected the global secret to be unchanged")
+	}
+	raw, err := json.Marshal(gs.Spec)
+	if err != nil || !strings.Contains(string(raw), value) {
+		t.Errorf("expected the json encoding to contain the value, got %s (%v)", raw, err)
+	}
+}
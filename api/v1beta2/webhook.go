@@ -0,0 +1,197 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+//+kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// the validator of the global objects, which is shared by the webhooks of both kinds
+// +kubebuilder:object:generate=false
+type GlobalValidator struct {
+	// the client is used to create the subjectaccessreviews
+	Client client.Client
+
+	// the namespaces, which are protected by the manager
+	ProtectedNamespaces []string
+}
+
+// validate the namespaces, which are targeted by a global object
+//
+// the operator replicates the data with its own cluster-wide permissions, so the
+// requesting user must be allowed to create, update and delete the replicated
+// resource in every namespace, which is matched by the namespace regexpressions,
+// otherwise the global object would grant more rights, than the user has, with the
+// rollout, the user must also be allowed to patch the workloads, which are restarted
+//
+// a user, who is not allowed to do so in all namespaces, may only use namespace
+// regexpressions, which match a fixed list of names, see [CompiledNamespacesRegex.Names]
+//
+// the protected namespaces are only checked, if the global object opts in with the
+// annotation [AnnotationAllowProtectedNamespaces], since they are avoided otherwise
+func (v *GlobalValidator) validateTargetNamespaces(ctx context.Context, obj metav1.Object, nsr NamespacesRegex, resource string, rollout bool) error {
+
+	allow, err := AllowsProtectedNamespaces(obj.GetAnnotations())
+	if err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+
+	cnsr, err := nsr.Compile()
+	if err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+	if !allow {
+		cnsr = cnsr.Protect(v.ProtectedNamespaces)
+	}
+
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return apierrors.NewInternalError(err)
+	}
+
+	var perms = []permission{{resources: []string{resource}, verbs: replicationVerbs}}
+	if rollout {
+		perms = append(perms, rolloutPermission)
+	}
+
+	// a user, who may replicate the resource in all namespaces, may target any namespace
+	if allowed, err := v.canAll(ctx, req, "", perms); err != nil || allowed {
+		return err
+	}
+
+	// every other user may only target a fixed list of namespaces, an open pattern, e.g.
+	// "^team-a-", would also match the namespaces, which are created after this request,
+	// so the namespaces, which do not exist yet, are checked as well
+	names, ok := cnsr.Names()
+	if !ok {
+		return fmt.Errorf("user %q is not allowed to %s in all namespaces, so the namespace regexpressions "+
+			"may only match a fixed list of names, e.g. \"^(team-a-dev|team-a-prod)$\" or the match mode Exact", req.UserInfo.Username, describe(perms))
+	}
+
+	for _, ns := range names {
+		allowed, err := v.canAll(ctx, req, ns, perms)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return fmt.Errorf("user %q is not allowed to %s in the namespace %s, which is matched by the namespace regexpressions",
+				req.UserInfo.Username, describe(perms), ns)
+		}
+	}
+	return nil
+}
+
+// validate the rollout strategy of a global object, the patterns of the waves must
+// compile with the match mode of the strategy and the batch interval must be positive
+func validateRolloutStrategy(rs *RolloutStrategy) error {
+	if rs == nil {
+		return nil
+	}
+	if _, err := rs.Compile(); err != nil {
+		return apierrors.NewBadRequest(err.Error())
+	}
+	if rs.BatchInterval != nil && rs.BatchInterval.Duration <= 0 {
+		return apierrors.NewBadRequest(fmt.Sprintf("the batchInterval %s of the rollout strategy must be positive", rs.BatchInterval.Duration))
+	}
+	return nil
+}
+
+// the verbs, which the operator uses on the replicated resources, the objects are
+// immutable, so an update deletes and recreates them
+var replicationVerbs = []string{"create", "update", "delete"}
+
+// the permission, which the operator uses to restart the workloads, which consume the
+// replicated resources, see spec.rollout
+var rolloutPermission = permission{group: "apps", resources: []string{"deployments", "statefulsets", "daemonsets"}, verbs: []string{"patch"}}
+
+// the verbs on the resources of an api group, which the operator uses on behalf of a
+// global object
+type permission struct {
+	group     string
+	resources []string
+	verbs     []string
+}
+
+// describe the permissions for an error message, e.g. "create, update and delete secrets
+// and patch deployments, statefulsets and daemonsets"
+func describe(perms []permission) string {
+	var parts = make([]string, 0, len(perms))
+	for _, p := range perms {
+		parts = append(parts, joinWords(p.verbs)+" "+joinWords(p.resources))
+	}
+	return strings.Join(parts, " and ")
+}
+
+// join the items to "a, b and c"
+func joinWords(items []string) string {
+	if len(items) < 2 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
+
+// check with subjectaccessreviews, whether the requesting user is allowed to use all
+// verbs of the permissions in the namespace, an empty namespace stands for all namespaces
+func (v *GlobalValidator) canAll(ctx context.Context, req admission.Request, namespace string, perms []permission) (bool, error) {
+	for _, p := range perms {
+		for _, resource := range p.resources {
+			for _, verb := range p.verbs {
+				if allowed, err := v.can(ctx, req, namespace, p.group, resource, verb); err != nil || !allowed {
+					return false, err
+				}
+			}
+		}
+	}
+	return true, nil
+}
+
+// check with a subjectaccessreview, whether the requesting user is allowed to use the
+// verb on the resource in the namespace, an empty namespace stands for all namespaces
+func (v *GlobalValidator) can(ctx context.Context, req admission.Request, namespace, group, resource, verb string) (bool, error) {
+
+	var sar = &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   req.UserInfo.Username,
+			UID:    req.UserInfo.UID,
+			Groups: req.UserInfo.Groups,
+			Extra:  make(map[string]authorizationv1.ExtraValue, len(req.UserInfo.Extra)),
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      verb,
+				Group:     group,
+				Resource:  resource,
+			},
+		},
+	}
+	for k, val := range req.UserInfo.Extra {
+		sar.Spec.Extra[k] = authorizationv1.ExtraValue(val)
+	}
+	if err := v.Client.Create(ctx, sar); err != nil {
+		return false, apierrors.NewInternalError(err)
+	}
+	return sar.Status.Allowed, nil
+}
@@ -0,0 +1,208 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta2
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// a client, which answers the subjectaccessreviews with the allowed namespaces,
+// the empty namespace stands for all namespaces, the denied verb or resource is never
+// allowed, the workloads are only allowed in the api group apps
+type sarClient struct {
+	client.Client
+	allowed map[string]bool
+	denied  string
+	reviews []string
+}
+
+func (c *sarClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if sar, ok := obj.(*authorizationv1.SubjectAccessReview); ok {
+		var attrs = sar.Spec.ResourceAttributes
+		c.reviews = append(c.reviews, attrs.Namespace)
+		sar.Status.Allowed = c.allowed[attrs.Namespace] && attrs.Verb != c.denied && attrs.Resource != c.denied
+		if attrs.Resource == "deployments" || attrs.Resource == "statefulsets" || attrs.Resource == "daemonsets" {
+			sar.Status.Allowed = sar.Status.Allowed && attrs.Group == "apps"
+		}
+		return nil
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func TestValidateTargetNamespaces(t *testing.T) {
+	var ctx = admission.NewContextWithRequest(context.Background(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{UserInfo: authenticationv1.UserInfo{Username: "jane"}},
+	})
+
+	var namespaces []client.Object
+	for _, name := range []string{"kube-system", "team-a-dev", "team-a-prod", "team-b-dev"} {
+		var ns = &v1.Namespace{}
+		ns.Name = name
+		namespaces = append(namespaces, ns)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		annotation string
+		matchRegex string
+		allowed    []string
+		denied     string
+		wantErr    bool
+		reviews    int
+	}{
+		{"cluster-wide permission", "", ".", []string{""}, "", false, 3},
+		{"own namespaces", "", "^team-a-(dev|prod)$", []string{"team-a-dev", "team-a-prod"}, "", false, 7},
+		{"foreign namespace", "", "^team-[ab]-dev$", []string{"team-a-dev"}, "", true, 5},
+		{"protected namespaces are skipped", "", "^kube-system$|^team-a-dev$", []string{"team-a-dev"}, "", false, 4},
+		{"invalid annotation", "yes please", ".", []string{""}, "", true, 0},
+		{"opted in without permission", "true", "^kube-system$|^team-a-dev$", []string{"team-a-dev"}, "", true, 2},
+		{"opted in with permission", "true", "^kube-(system|public)$", []string{"kube-system", "kube-public"}, "", false, 7},
+		{"opted in with cluster-wide permission", "true", "^kube-", []string{""}, "", false, 3},
+		{"create only", "", "^team-a-dev$", []string{"team-a-dev"}, "delete", true, 4},
+		{"create only cluster-wide", "", "^team-a-dev$", []string{"", "team-a-dev"}, "update", true, 4},
+		{"open pattern", "", "^team-a-", []string{"team-a-dev", "team-a-prod"}, "", true, 1},
+		{"namespace, which does not exist yet", "", "^team-c$", []string{"team-c"}, "", false, 4},
+		{"foreign namespace, which does not exist yet", "", "^team-[ac]-dev$", []string{"team-a-dev"}, "", true, 5},
+	} {
+		var c = &sarClient{
+			Client:  fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).WithObjects(namespaces...).Build(),
+			allowed: make(map[string]bool),
+			denied:  tc.denied,
+		}
+		for _, ns := range tc.allowed {
+			c.allowed[ns] = true
+		}
+		var v = &globalSecretValidator{&GlobalValidator{Client: c, ProtectedNamespaces: []string{"kube-system", "kube-public"}}}
+
+		var gs = &GlobalSecret{}
+		gs.Name = "gs"
+		gs.Spec.Namespaces = NamespacesRegex{MatchRegex: []string{tc.matchRegex}}
+		if tc.annotation != "" {
+			gs.Annotations = map[string]string{AnnotationAllowProtectedNamespaces: tc.annotation}
+		}
+
+		err := v.ValidateCreate(ctx, gs)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+		}
+		if len(c.reviews) != tc.reviews {
+			t.Errorf("%s: expected %d subjectaccessreviews, got %q", tc.name, tc.reviews, c.reviews)
+		}
+	}
+}
+
+func TestValidateRolloutPermissions(t *testing.T) {
+	var ctx = admission.NewContextWithRequest(context.Background(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{UserInfo: authenticationv1.UserInfo{Username: "jane"}},
+	})
+
+	for _, tc := range []struct {
+		name       string
+		matchRegex string
+		allowed    []string
+		denied     string
+		wantErr    bool
+		reviews    int
+	}{
+		// the cluster-wide review of the configmaps, deployments, statefulsets and daemonsets
+		{"cluster-wide permission", ".", []string{""}, "", false, 6},
+		{"cluster-wide without workloads", ".", []string{""}, "daemonsets", true, 6},
+		{"own namespaces", "^team-a-(dev|prod)$", []string{"team-a-dev", "team-a-prod"}, "", false, 13},
+		{"own namespaces without patch", "^team-a-dev$", []string{"team-a-dev"}, "patch", true, 5},
+		{"own namespaces without workloads", "^team-a-dev$", []string{"", "team-a-dev"}, "statefulsets", true, 10},
+	} {
+		var c = &sarClient{
+			Client:  fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build(),
+			allowed: make(map[string]bool),
+			denied:  tc.denied,
+		}
+		for _, ns := range tc.allowed {
+			c.allowed[ns] = true
+		}
+		var v = &globalConfigValidator{&GlobalValidator{Client: c}}
+
+		var gc = &GlobalConfig{}
+		gc.Name = "gc"
+		gc.Spec.Namespaces = NamespacesRegex{MatchRegex: []string{tc.matchRegex}}
+		gc.Spec.Rollout = true
+
+		err := v.ValidateCreate(ctx, gc)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+		}
+		if len(c.reviews) != tc.reviews {
+			t.Errorf("%s: expected %d subjectaccessreviews, got %q", tc.name, tc.reviews, c.reviews)
+		}
+
+		// without the rollout, the workloads are not reviewed
+		gc.Spec.Rollout = false
+		if err := v.ValidateCreate(ctx, gc); err != nil {
+			t.Errorf("%s: the workloads were reviewed without the rollout: %v", tc.name, err)
+		}
+	}
+}
+
+func TestDescribePermissions(t *testing.T) {
+	var perms = []permission{{resources: []string{"secrets"}, verbs: replicationVerbs}, rolloutPermission}
+	if got, want := describe(perms), "create, update and delete secrets and patch deployments, statefulsets and daemonsets"; got != want {
+		t.Errorf("expected %q, got %q", want, got)
+	}
+}
+
+func TestValidateRolloutStrategy(t *testing.T) {
+	var ctx = admission.NewContextWithRequest(context.Background(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{UserInfo: authenticationv1.UserInfo{Username: "jane"}},
+	})
+	var interval = func(d time.Duration) *metav1.Duration { return &metav1.Duration{Duration: d} }
+	for _, tc := range []struct {
+		name     string
+		strategy *RolloutStrategy
+		wantErr  bool
+	}{
+		{"no strategy", nil, false},
+		{"valid waves", &RolloutStrategy{Waves: []RolloutWave{{Name: "dev", MatchRegex: []string{"-dev$"}}}, BatchInterval: interval(time.Second)}, false},
+		{"invalid regex", &RolloutStrategy{Waves: []RolloutWave{{Name: "dev", MatchRegex: []string{"("}}}}, true},
+		{"escaping anchored regex", &RolloutStrategy{MatchMode: MatchModeAnchoredRegex, Waves: []RolloutWave{{Name: "dev", MatchRegex: []string{"a)|(?:.*"}}}}, true},
+		{"unknown match mode", &RolloutStrategy{MatchMode: "Fuzzy", Waves: []RolloutWave{{Name: "dev", MatchRegex: []string{"a"}}}}, true},
+		{"zero batch interval", &RolloutStrategy{BatchInterval: interval(0)}, true},
+		{"negative batch interval", &RolloutStrategy{BatchInterval: interval(-time.Second)}, true},
+	} {
+		var c = &sarClient{Client: fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build(), allowed: map[string]bool{"": true}}
+		var v = &globalSecretValidator{&GlobalValidator{Client: c}}
+
+		var gs = &GlobalSecret{}
+		gs.Name = "gs"
+		gs.Spec.Namespaces = NamespacesRegex{MatchRegex: []string{"."}}
+		gs.Spec.RolloutStrategy = tc.strategy
+
+		if err := v.ValidateCreate(ctx, gs); (err != nil) != tc.wantErr {
+			t.Errorf("%s: unexpected error %v", tc.name, err)
+		}
+	}
+}
@@ -23,7 +23,7 @@ package v1beta2
 
 import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1"
-	runtime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime"
 )
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
@@ -126,6 +126,21 @@ func (in *GlobalConfigSpec) DeepCopyInto(out *GlobalConfigSpec) {
 			(*out)[key] = val
 		}
 	}
+	if in.RolloutStrategy != nil {
+		in, out := &in.RolloutStrategy, &out.RolloutStrategy
+		*out = new(RolloutStrategy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RevisionHistoryLimit != nil {
+		in, out := &in.RevisionHistoryLimit, &out.RevisionHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ResyncInterval != nil {
+		in, out := &in.ResyncInterval, &out.ResyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GlobalConfigSpec.
@@ -146,6 +161,16 @@ func (in *GlobalConfigStatus) DeepCopyInto(out *GlobalConfigStatus) {
 		*out = make([]DeployedConfigMap, len(*in))
 		copy(*out, *in)
 	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(RolloutStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Plan != nil {
+		in, out := &in.Plan, &out.Plan
+		*out = new(ReplicationPlan)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]v1.Condition, len(*in))
@@ -165,6 +190,104 @@ func (in *GlobalConfigStatus) DeepCopy() *GlobalConfigStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GlobalReplicationPolicy) DeepCopyInto(out *GlobalReplicationPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GlobalReplicationPolicy.
+func (in *GlobalReplicationPolicy) DeepCopy() *GlobalReplicationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(GlobalReplicationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GlobalReplicationPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GlobalReplicationPolicyList) DeepCopyInto(out *GlobalReplicationPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GlobalReplicationPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GlobalReplicationPolicyList.
+func (in *GlobalReplicationPolicyList) DeepCopy() *GlobalReplicationPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(GlobalReplicationPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GlobalReplicationPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GlobalReplicationPolicySpec) DeepCopyInto(out *GlobalReplicationPolicySpec) {
+	*out = *in
+	if in.SourceNamespaces != nil {
+		in, out := &in.SourceNamespaces, &out.SourceNamespaces
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.TargetNamespaces != nil {
+		in, out := &in.TargetNamespaces, &out.TargetNamespaces
+		*out = new(NamespacesRegex)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ForbiddenSecretTypes != nil {
+		in, out := &in.ForbiddenSecretTypes, &out.ForbiddenSecretTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaxFanOut != nil {
+		in, out := &in.MaxFanOut, &out.MaxFanOut
+		*out = new(int32)
+		**out = **in
+	}
+	if in.RequiredLabels != nil {
+		in, out := &in.RequiredLabels, &out.RequiredLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GlobalReplicationPolicySpec.
+func (in *GlobalReplicationPolicySpec) DeepCopy() *GlobalReplicationPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GlobalReplicationPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GlobalSecret) DeepCopyInto(out *GlobalSecret) {
 	*out = *in
@@ -230,11 +353,26 @@ func (in *GlobalSecretSpec) DeepCopyInto(out *GlobalSecretSpec) {
 	in.Namespaces.DeepCopyInto(&out.Namespaces)
 	if in.Data != nil {
 		in, out := &in.Data, &out.Data
-		*out = make(map[string]string, len(*in))
+		*out = make(SecretData, len(*in))
 		for key, val := range *in {
 			(*out)[key] = val
 		}
 	}
+	if in.RolloutStrategy != nil {
+		in, out := &in.RolloutStrategy, &out.RolloutStrategy
+		*out = new(RolloutStrategy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RevisionHistoryLimit != nil {
+		in, out := &in.RevisionHistoryLimit, &out.RevisionHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ResyncInterval != nil {
+		in, out := &in.ResyncInterval, &out.ResyncInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GlobalSecretSpec.
@@ -255,6 +393,16 @@ func (in *GlobalSecretStatus) DeepCopyInto(out *GlobalSecretStatus) {
 		*out = make([]DeployedSecret, len(*in))
 		copy(*out, *in)
 	}
+	if in.Rollout != nil {
+		in, out := &in.Rollout, &out.Rollout
+		*out = new(RolloutStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Plan != nil {
+		in, out := &in.Plan, &out.Plan
+		*out = new(ReplicationPlan)
+		(*in).DeepCopyInto(*out)
+	}
 	if in.Conditions != nil {
 		in, out := &in.Conditions, &out.Conditions
 		*out = make([]v1.Condition, len(*in))
@@ -298,3 +446,130 @@ func (in *NamespacesRegex) DeepCopy() *NamespacesRegex {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReplicationPlan) DeepCopyInto(out *ReplicationPlan) {
+	*out = *in
+	if in.Create != nil {
+		in, out := &in.Create, &out.Create
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Update != nil {
+		in, out := &in.Update, &out.Update
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Delete != nil {
+		in, out := &in.Delete, &out.Delete
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conflicts != nil {
+		in, out := &in.Conflicts, &out.Conflicts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReplicationPlan.
+func (in *ReplicationPlan) DeepCopy() *ReplicationPlan {
+	if in == nil {
+		return nil
+	}
+	out := new(ReplicationPlan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutStatus) DeepCopyInto(out *RolloutStatus) {
+	*out = *in
+	if in.PausedUntil != nil {
+		in, out := &in.PausedUntil, &out.PausedUntil
+		*out = (*in).DeepCopy()
+	}
+	if in.NextBatchAfter != nil {
+		in, out := &in.NextBatchAfter, &out.NextBatchAfter
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutStatus.
+func (in *RolloutStatus) DeepCopy() *RolloutStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutStrategy) DeepCopyInto(out *RolloutStrategy) {
+	*out = *in
+	if in.Waves != nil {
+		in, out := &in.Waves, &out.Waves
+		*out = make([]RolloutWave, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	out.PauseBetweenWaves = in.PauseBetweenWaves
+	if in.BatchInterval != nil {
+		in, out := &in.BatchInterval, &out.BatchInterval
+		*out = new(v1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutStrategy.
+func (in *RolloutStrategy) DeepCopy() *RolloutStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RolloutWave) DeepCopyInto(out *RolloutWave) {
+	*out = *in
+	if in.MatchRegex != nil {
+		in, out := &in.MatchRegex, &out.MatchRegex
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RolloutWave.
+func (in *RolloutWave) DeepCopy() *RolloutWave {
+	if in == nil {
+		return nil
+	}
+	out := new(RolloutWave)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in SecretData) DeepCopyInto(out *SecretData) {
+	{
+		in := &in
+		*out = make(SecretData, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SecretData.
+func (in SecretData) DeepCopy() SecretData {
+	if in == nil {
+		return nil
+	}
+	out := new(SecretData)
+	in.DeepCopyInto(out)
+	return *out
+}
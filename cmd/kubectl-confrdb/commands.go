@@ -0,0 +1,362 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	globalsv1beta2 "github.com/jnnkrdb/configrdb/api/v1beta2"
+	"github.com/jnnkrdb/configrdb/internal/audit"
+	"github.com/jnnkrdb/configrdb/internal/orphans"
+)
+
+// the parts of a globalconfig or globalsecret, which are needed to inspect its copies
+type global struct {
+	obj        client.Object
+	namespaces globalsv1beta2.NamespacesRegex
+
+	// the desired data of the copies, the data of a globalsecret is decoded
+	data map[string]string
+
+	// the type of the secrets, empty for a globalconfig
+	secretType string
+}
+
+// get a globalconfig (gc) or a globalsecret (gs) by name
+func getGlobal(ctx context.Context, c client.Client, kind, namespace, name string) (*global, error) {
+
+	var key = types.NamespacedName{Namespace: namespace, Name: name}
+	switch kind {
+	case "gc", "globalconfig", "globalconfigs":
+		var gc = &globalsv1beta2.GlobalConfig{}
+		if err := c.Get(ctx, key, gc); err != nil {
+			return nil, err
+		}
+		return &global{obj: gc, namespaces: gc.Spec.Namespaces, data: gc.Spec.Data}, nil
+
+	case "gs", "globalsecret", "globalsecrets":
+		var gs = &globalsv1beta2.GlobalSecret{}
+		if err := c.Get(ctx, key, gs); err != nil {
+			return nil, err
+		}
+		var data = make(map[string]string, len(gs.Spec.Data))
+		for k, encoded := range gs.Spec.Data {
+			decoded, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return nil, fmt.Errorf("invalid base64 data in key %q: %w", k, err)
+			}
+			data[k] = string(decoded)
+		}
+		// a globalsecret without type replicates secrets of the default type
+		var secretType = gs.Spec.Type
+		if secretType == "" {
+			secretType = globalsv1beta2.DefaultSecretType
+		}
+		return &global{obj: gs, namespaces: gs.Spec.Namespaces, data: data, secretType: secretType}, nil
+	}
+	return nil, fmt.Errorf("unknown kind %q, expected gc or gs", kind)
+}
+
+// get the global object of the arguments <gc|gs> NAME
+func globalFromArgs(ctx context.Context, c client.Client, opts options) (*global, error) {
+	if len(opts.args) != 2 {
+		return nil, fmt.Errorf("expected the arguments <gc|gs> NAME")
+	}
+	return getGlobal(ctx, c, opts.args[0], opts.namespace, opts.args[1])
+}
+
+// calculate the namespaces of a global object, like the operator does, the protected
+// namespaces are avoided, unless the global object opts in, and only the target
+// namespaces of the operator are part of either list, see --target-namespaces
+func (g *global) calculateNamespaces(ctx context.Context, c client.Client, opts options) (matches, avoids []v1.Namespace, err error) {
+
+	cnsr, err := g.namespaces.Compile()
+	if err != nil {
+		return nil, nil, err
+	}
+	if allow, _ := globalsv1beta2.AllowsProtectedNamespaces(g.obj.GetAnnotations()); !allow {
+		cnsr = cnsr.Protect(opts.protectedNamespaces)
+	}
+	cnsr = cnsr.Restrict(opts.targetNamespaces)
+	matches, avoids, err = cnsr.CalculateNamespaces(logr.Discard(), ctx, c)
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Name < matches[j].Name })
+	sort.Slice(avoids, func(i, j int) bool { return avoids[i].Name < avoids[j].Name })
+	return
+}
+
+// get the copy of a global object in a namespace, nil if it does not exist
+func (g *global) getCopy(ctx context.Context, c client.Client, namespace string) (client.Object, map[string]string, error) {
+
+	var key = types.NamespacedName{Namespace: namespace, Name: g.obj.GetName()}
+	if _, ok := g.obj.(*globalsv1beta2.GlobalConfig); ok {
+		var cm = &v1.ConfigMap{}
+		if err := c.Get(ctx, key, cm); err != nil {
+			return nil, nil, client.IgnoreNotFound(err)
+		}
+		return cm, cm.Data, nil
+	}
+
+	var secret = &v1.Secret{}
+	if err := c.Get(ctx, key, secret); err != nil {
+		return nil, nil, client.IgnoreNotFound(err)
+	}
+	var data = make(map[string]string, len(secret.Data))
+	for k, v := range secret.Data {
+		data[k] = string(v)
+	}
+	return secret, data, nil
+}
+
+// check, whether a copy carries the uid label of the global object
+func (g *global) owns(obj client.Object) bool {
+	return obj.GetLabels()[globalsv1beta2.LabelUID] == string(g.obj.GetUID())
+}
+
+// targets <gc|gs> NAME
+func targets(ctx context.Context, c client.Client, out io.Writer, opts options) error {
+
+	g, err := globalFromArgs(ctx, c, opts)
+	if err != nil {
+		return err
+	}
+	matches, avoids, err := g.calculateNamespaces(ctx, c, opts)
+	if err != nil {
+		return err
+	}
+
+	var protected = make(map[string]bool, len(opts.protectedNamespaces))
+	for _, ns := range opts.protectedNamespaces {
+		protected[ns] = true
+	}
+
+	var w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tTARGET")
+	for i := range matches {
+		fmt.Fprintf(w, "%s\tmatched\n", matches[i].Name)
+	}
+	for i := range avoids {
+		if protected[avoids[i].Name] {
+			fmt.Fprintf(w, "%s\tavoided (protected)\n", avoids[i].Name)
+		} else {
+			fmt.Fprintf(w, "%s\tavoided\n", avoids[i].Name)
+		}
+	}
+	return w.Flush()
+}
+
+// diff <gc|gs> NAME
+func diff(ctx context.Context, c client.Client, out io.Writer, opts options) error {
+
+	g, err := globalFromArgs(ctx, c, opts)
+	if err != nil {
+		return err
+	}
+	matches, avoids, err := g.calculateNamespaces(ctx, c, opts)
+	if err != nil {
+		return err
+	}
+
+	var differences int
+	for i := range matches {
+		obj, live, err := g.getCopy(ctx, c, matches[i].Name)
+		if err != nil {
+			return err
+		}
+		if obj == nil {
+			fmt.Fprintf(out, "namespace %s: missing\n", matches[i].Name)
+			differences++
+			continue
+		}
+
+		// the operator never overwrites an object, which is not owned by the global object
+		if !g.owns(obj) {
+			fmt.Fprintf(out, "namespace %s: conflict (not owned)\n", matches[i].Name)
+			differences++
+			continue
+		}
+
+		var changes = diffData(g.data, live)
+		if secret, ok := obj.(*v1.Secret); ok && string(secret.Type) != g.secretType {
+			changes = append([]string{fmt.Sprintf("  ~ type: %s -> %s", secret.Type, g.secretType)}, changes...)
+		}
+		if len(changes) > 0 {
+			fmt.Fprintf(out, "namespace %s: outdated\n", matches[i].Name)
+			for _, change := range changes {
+				fmt.Fprintln(out, change)
+			}
+			differences++
+		}
+	}
+
+	for i := range avoids {
+		obj, _, err := g.getCopy(ctx, c, avoids[i].Name)
+		if err != nil {
+			return err
+		}
+		if obj != nil && g.owns(obj) {
+			fmt.Fprintf(out, "namespace %s: to be removed\n", avoids[i].Name)
+			differences++
+		}
+	}
+
+	if differences == 0 {
+		fmt.Fprintln(out, "no differences")
+	}
+	return nil
+}
+
+// compare the desired data with the live data of a copy, the values are not
+// printed, since they can contain the data of a secret
+func diffData(desired, live map[string]string) []string {
+
+	var keys = make([]string, 0, len(desired)+len(live))
+	for k := range desired {
+		keys = append(keys, k)
+	}
+	for k := range live {
+		if _, ok := desired[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var changes []string
+	for _, k := range keys {
+		want, inDesired := desired[k]
+		got, inLive := live[k]
+		switch {
+		case !inLive:
+			changes = append(changes, "  + "+k)
+		case !inDesired:
+			changes = append(changes, "  - "+k)
+		case want != got:
+			changes = append(changes, "  ~ "+k)
+		}
+	}
+	return changes
+}
+
+// status [gc|gs]
+func status(ctx context.Context, c client.Client, out io.Writer, opts options) error {
+
+	var kinds = []string{"gc", "gs"}
+	if len(opts.args) > 0 {
+		kinds = opts.args[:1]
+	}
+
+	var listOpts []client.ListOption
+	if !opts.allNamespaces {
+		listOpts = append(listOpts, client.InNamespace(opts.namespace))
+	}
+
+	var w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAMESPACE\tNAME\tSYNCED\tREASON\tIN SYNC\tREVISION")
+	for _, kind := range kinds {
+		switch kind {
+		case "gc", "globalconfig", "globalconfigs":
+			var list = &globalsv1beta2.GlobalConfigList{}
+			if err := c.List(ctx, list, listOpts...); err != nil {
+				return err
+			}
+			for i := range list.Items {
+				var inSync int
+				for _, d := range list.Items[i].Status.DeployedConfigMaps {
+					if d.InSync {
+						inSync++
+					}
+				}
+				printStatus(w, "GlobalConfig", &list.Items[i], list.Items[i].Status.Conditions,
+					inSync, len(list.Items[i].Status.DeployedConfigMaps), list.Items[i].Status.CurrentRevision)
+			}
+
+		case "gs", "globalsecret", "globalsecrets":
+			var list = &globalsv1beta2.GlobalSecretList{}
+			if err := c.List(ctx, list, listOpts...); err != nil {
+				return err
+			}
+			for i := range list.Items {
+				var inSync int
+				for _, d := range list.Items[i].Status.DeployedSecrets {
+					if d.InSync {
+						inSync++
+					}
+				}
+				printStatus(w, "GlobalSecret", &list.Items[i], list.Items[i].Status.Conditions,
+					inSync, len(list.Items[i].Status.DeployedSecrets), list.Items[i].Status.CurrentRevision)
+			}
+
+		default:
+			return fmt.Errorf("unknown kind %q, expected gc or gs", kind)
+		}
+	}
+	return w.Flush()
+}
+
+// print a line of the status table
+func printStatus(w io.Writer, kind string, obj client.Object, conditions []metav1.Condition, inSync, total int, revision int64) {
+	var synced, reason = "Unknown", ""
+	if cond := meta.FindStatusCondition(conditions, globalsv1beta2.ConditionSynced); cond != nil {
+		synced, reason = string(cond.Status), cond.Reason
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d/%d\t%d\n", kind, obj.GetNamespace(), obj.GetName(), synced, reason, inSync, total, revision)
+}
+
+// orphans
+func listOrphans(ctx context.Context, c client.Client, out io.Writer) error {
+
+	found, err := orphans.Find(ctx, c, orphans.Scope{})
+	if err != nil {
+		return err
+	}
+	if len(found) == 0 {
+		fmt.Fprintln(out, "no orphans found")
+		return nil
+	}
+
+	var w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tNAMESPACE\tNAME\tGLOBAL UID")
+	for _, obj := range found {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", orphans.Kind(obj), obj.GetNamespace(), obj.GetName(), obj.GetLabels()[globalsv1beta2.LabelUID])
+	}
+	return w.Flush()
+}
+
+// verify-audit
+func verifyAudit(in io.Reader, out io.Writer, key []byte) error {
+
+	verified, err := audit.Verify(in, key)
+	if err != nil {
+		return fmt.Errorf("%w, after %d intact records", err, verified)
+	}
+	if verified == 0 {
+		return fmt.Errorf("no audit records found")
+	}
+	fmt.Fprintf(out, "%d records verified\n", verified)
+	return nil
+}
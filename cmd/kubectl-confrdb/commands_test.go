@@ -0,0 +1,135 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	globalsv1beta2 "github.com/jnnkrdb/configrdb/api/v1beta2"
+)
+
+// create a fake client with a globalsecret, which matches the namespaces with the suffix "-dev"
+func newPluginClient(t *testing.T) client.Client {
+	t.Helper()
+
+	var objs []client.Object
+	for _, name := range []string{"kube-system", "team-a-dev", "team-b-dev", "team-c-dev", "team-d-dev", "team-a-prod"} {
+		var ns = &v1.Namespace{}
+		ns.Name = name
+		objs = append(objs, ns)
+	}
+
+	var gs = &globalsv1beta2.GlobalSecret{}
+	gs.Name, gs.Namespace, gs.UID = "pull-secret", "default", "gs-uid"
+	gs.Spec.Namespaces = globalsv1beta2.NamespacesRegex{MatchRegex: []string{"-dev$", "^kube-"}}
+	gs.Spec.Type = "Opaque"
+	gs.Spec.Data = map[string]string{
+		"user":     base64.StdEncoding.EncodeToString([]byte("jane")),
+		"password": base64.StdEncoding.EncodeToString([]byte("secret")),
+	}
+	objs = append(objs, gs)
+
+	var secret = func(namespace string, data map[string]string) *v1.Secret {
+		var s = &v1.Secret{Type: "Opaque", Data: map[string][]byte{}}
+		s.Name, s.Namespace, s.Labels = "pull-secret", namespace, globalsv1beta2.MatchingLables(gs.UID)
+		for k, v := range data {
+			s.Data[k] = []byte(v)
+		}
+		return s
+	}
+	objs = append(objs,
+		secret("team-a-dev", map[string]string{"user": "jane", "password": "secret"}),
+		secret("team-b-dev", map[string]string{"user": "jane", "password": "old", "token": "x"}),
+		secret("team-a-prod", map[string]string{"user": "jane", "password": "secret"}),
+	)
+
+	// team-d-dev contains a secret with the same name, which is not owned by the globalsecret
+	var unowned = &v1.Secret{Type: "Opaque"}
+	unowned.Name, unowned.Namespace = "pull-secret", "team-d-dev"
+	objs = append(objs, unowned)
+
+	return fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+}
+
+func TestTargets(t *testing.T) {
+	var out bytes.Buffer
+	var opts = options{namespace: "default", protectedNamespaces: []string{"kube-system"}, args: []string{"gs", "pull-secret"}}
+	if err := targets(context.Background(), newPluginClient(t), &out, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"team-a-dev   matched", "team-c-dev   matched", "team-a-prod  avoided", "kube-system  avoided (protected)"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in the output:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	var out bytes.Buffer
+	var opts = options{namespace: "default", protectedNamespaces: []string{"kube-system"}, args: []string{"gs", "pull-secret"}}
+	if err := diff(context.Background(), newPluginClient(t), &out, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	var want = strings.Join([]string{
+		"namespace team-b-dev: outdated",
+		"  ~ password",
+		"  - token",
+		"namespace team-c-dev: missing",
+		"namespace team-d-dev: conflict (not owned)",
+		"namespace team-a-prod: to be removed",
+	}, "\n") + "\n"
+	if out.String() != want {
+		t.Errorf("expected the output:\n%s\ngot:\n%s", want, out.String())
+	}
+}
+
+func TestTargetNamespaces(t *testing.T) {
+	var c = newPluginClient(t)
+	var opts = options{
+		namespace: "default", protectedNamespaces: []string{"kube-system"}, args: []string{"gs", "pull-secret"},
+		targetNamespaces: []string{"team-b-dev", "team-a-prod"},
+	}
+
+	// the namespaces, which are no target of the operator, are neither matched nor avoided
+	var out bytes.Buffer
+	if err := targets(context.Background(), c, &out, opts); err != nil {
+		t.Fatal(err)
+	}
+	var want = "NAMESPACE    TARGET\nteam-b-dev   matched\nteam-a-prod  avoided\n"
+	if out.String() != want {
+		t.Errorf("expected the output:\n%s\ngot:\n%s", want, out.String())
+	}
+
+	out.Reset()
+	if err := diff(context.Background(), c, &out, opts); err != nil {
+		t.Fatal(err)
+	}
+	want = "namespace team-b-dev: outdated\n  ~ password\n  - token\nnamespace team-a-prod: to be removed\n"
+	if out.String() != want {
+		t.Errorf("expected the output:\n%s\ngot:\n%s", want, out.String())
+	}
+}
@@ -0,0 +1,198 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kubectl-confrdb is a kubectl plugin, to inspect the GlobalConfigs and
+// GlobalSecrets and their replicated ConfigMaps and Secrets
+//
+//	kubectl confrdb targets <gc|gs> NAME   list the matched and avoided namespaces
+//	kubectl confrdb diff <gc|gs> NAME      compare the desired data with the replicated copies
+//	kubectl confrdb status [gc|gs]         summarise the sync state of the global objects
+//	kubectl confrdb orphans                find the copies, whose global object does not exist anymore
+//	kubectl confrdb verify-audit FILE      verify the chains of the audit records of the operator
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	globalsv1beta2 "github.com/jnnkrdb/configrdb/api/v1beta2"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(globalsv1beta2.AddToScheme(scheme))
+}
+
+const usage = `Usage: kubectl confrdb [--kubeconfig FILE] COMMAND [FLAGS]
+
+Commands:
+  targets <gc|gs> NAME   list the namespaces, which are matched and avoided by a global object
+  diff <gc|gs> NAME      compare the desired data of a global object with its replicated copies
+  status [gc|gs]         summarise the sync state of the global objects
+  orphans                find the replicated copies, whose global object does not exist anymore
+  verify-audit FILE      verify, that no audit record was modified, removed or reordered, "-" reads stdin
+
+Flags of the commands:
+  -n, --namespace NAME   the namespace of the global objects, defaults to the namespace of the context
+  -A, --all-namespaces   list the global objects of all namespaces (status)
+  --protected-namespaces the namespaces, which are protected by the operator (targets, diff)
+  --target-namespaces    the only namespaces, which are replicated into by the operator, defaults to all (targets, diff)
+  --key-file FILE        the key, which signs the audit records (verify-audit)
+`
+
+func main() {
+	flag.Usage = func() { fmt.Fprint(flag.CommandLine.Output(), usage) }
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(context.Background(), flag.Arg(0), flag.Args()[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+// the options, which are shared by the commands
+type options struct {
+	namespace           string
+	allNamespaces       bool
+	protectedNamespaces []string
+	targetNamespaces    []string
+	keyFile             string
+	args                []string
+}
+
+// parse the flags of a command and execute it
+func run(ctx context.Context, command string, args []string) error {
+
+	var opts = options{}
+	var protected, target string
+	var fs = flag.NewFlagSet(command, flag.ContinueOnError)
+	fs.StringVar(&opts.namespace, "n", "", "the namespace of the global objects")
+	fs.StringVar(&opts.namespace, "namespace", "", "the namespace of the global objects")
+	fs.BoolVar(&opts.allNamespaces, "A", false, "list the global objects of all namespaces")
+	fs.BoolVar(&opts.allNamespaces, "all-namespaces", false, "list the global objects of all namespaces")
+	fs.StringVar(&protected, "protected-namespaces", strings.Join(globalsv1beta2.DefaultProtectedNamespaces, ","),
+		"comma separated list of the namespaces, which are protected by the operator")
+	fs.StringVar(&target, "target-namespaces", "",
+		"comma separated list of the only namespaces, which are replicated into by the operator, defaults to all namespaces")
+	fs.StringVar(&opts.keyFile, "key-file", "", "the key, which signs the audit records")
+	// the flags may follow the positional arguments, e.g. "targets gc NAME -n NAMESPACE"
+	for {
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		opts.args = append(opts.args, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	opts.protectedNamespaces = splitList(protected)
+	opts.targetNamespaces = splitList(target)
+
+	// the audit records are verified without the cluster
+	if command == "verify-audit" {
+		return verifyAuditFile(opts)
+	}
+
+	if opts.namespace == "" {
+		opts.namespace = contextNamespace()
+	}
+
+	cfg, err := ctrl.GetConfig()
+	if err != nil {
+		return err
+	}
+	c, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "targets":
+		return targets(ctx, c, os.Stdout, opts)
+	case "diff":
+		return diff(ctx, c, os.Stdout, opts)
+	case "status":
+		return status(ctx, c, os.Stdout, opts)
+	case "orphans":
+		return listOrphans(ctx, c, os.Stdout)
+	}
+	return fmt.Errorf("unknown command %q, see --help", command)
+}
+
+// verify the audit records of a file or of stdin
+func verifyAuditFile(opts options) error {
+	if len(opts.args) != 1 {
+		return fmt.Errorf("expected a file, e.g. verify-audit audit.jsonl")
+	}
+	if opts.keyFile == "" {
+		return fmt.Errorf("expected the key of the records, e.g. verify-audit audit.jsonl --key-file audit.key")
+	}
+	key, err := os.ReadFile(opts.keyFile)
+	if err != nil {
+		return err
+	}
+	if opts.args[0] == "-" {
+		return verifyAudit(os.Stdin, os.Stdout, key)
+	}
+	f, err := os.Open(opts.args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return verifyAudit(f, os.Stdout, key)
+}
+
+// split a comma separated list, the empty items are dropped
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// get the namespace of the current kubeconfig context
+func contextNamespace() string {
+	var rules = clientcmd.NewDefaultClientConfigLoadingRules()
+	if f := flag.Lookup("kubeconfig"); f != nil {
+		rules.ExplicitPath = f.Value.String()
+	}
+	ns, _, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).Namespace()
+	if err != nil || ns == "" {
+		return "default"
+	}
+	return ns
+}
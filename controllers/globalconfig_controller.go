@@ -19,31 +19,67 @@ package controllers
 import (
 	"context"
 	"fmt"
-	"reflect"
+	"regexp"
+	"sort"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	globalsv1beta2 "github.com/jnnkrdb/configrdb/api/v1beta2"
+	"github.com/jnnkrdb/configrdb/internal/audit"
+	"github.com/jnnkrdb/configrdb/internal/sharding"
 )
 
 // GlobalConfigReconciler reconciles a GlobalConfig object
 type GlobalConfigReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// the namespaces, which are always avoided, unless the global object
+	// opts in with the annotation [AnnotationAllowProtectedNamespaces]
+	ProtectedNamespaces []string
+
+	// the namespaces, whose global objects are reconciled, all namespaces if empty
+	WatchNamespaces []string
+
+	// the only namespaces, which are replicated into, all namespaces if empty
+	TargetNamespaces []string
+
+	// the audit of the changes to the replicated objects and the workloads, nil records nothing
+	Audit *audit.Auditor
+
+	// the key of the content hashes, see ContentHash
+	HashKey []byte
+
+	// the shards of this replica, nil reconciles all global objects
+	Sharder *sharding.Sharder
+
+	// the interval, in which the global objects are reconciled again without an
+	// event, unless they set spec.resyncInterval, 0 disables the periodic reconciliation
+	ResyncPeriod time.Duration
+
+	// never restart the workloads, even if the global objects set spec.rollout
+	DisableWorkloadRollout bool
+
+	// the options of the controller, e.g. the number of concurrent reconciles and the rate limiter
+	Options controller.Options
+
+	// the compiled namespace regexpressions of the reconciled objects
+	regexCache namespacesRegexCache
 }
 
 //+kubebuilder:rbac:groups=globals.jnnkrdb.de,resources=globalconfigs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=globals.jnnkrdb.de,resources=globalconfigs/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=globals.jnnkrdb.de,resources=globalconfigs/finalizers,verbs=update
-//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;create;update;patch;delete
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=core,resources=namespaces,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
@@ -56,8 +92,18 @@ type GlobalConfigReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.14.1/pkg/reconcile
 func (r *GlobalConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	return traceReconcile(ctx, kindGlobalConfig, req, r.reconcile)
+}
+
+// reconcile a global object, the reconciliation is traced by [Reconcile]
+func (r *GlobalConfigReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	var _log = log.FromContext(ctx).WithName(fmt.Sprintf("GlobalConfig [%s]", req.NamespacedName))
-	_log.Info("start reconciling")
+	_log.V(1).Info("start reconciling")
+
+	// the cache can contain the global objects of the target namespaces, which are not watched
+	if !inNamespaces(r.WatchNamespaces, req.Namespace) {
+		return ctrl.Result{}, nil
+	}
 
 	// ---------------------------------------------------------------------------------------- get the current globalconfig from the reconcile request
 	// create caching object
@@ -66,18 +112,27 @@ func (r *GlobalConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	// parse the ctrl.Request into a globalconfig
 	if err := r.Get(ctx, req.NamespacedName, gc); err != nil {
 
-		_log.Error(err, "error reconciling globalconfig")
-
 		// if the error is an "NotFound" error, then the globalconfig probably was deleted
 		// returning no error
 		if errors.IsNotFound(err) {
+			_log.V(1).Info("the globalconfig was deleted")
+			r.regexCache.forget(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
 
 		// if the error is something else, return the error
+		_log.Error(err, "error reconciling globalconfig")
 		return ctrl.Result{Requeue: true}, err
 	}
 
+	// the globalconfig can raise the verbosity of its logs
+	_log = objectLogger(_log, gc.Annotations, nil)
+
+	// the global objects of the other shards are reconciled by the other replicas
+	if r.Sharder != nil && !r.Sharder.Owns(gc.UID) {
+		return ctrl.Result{}, nil
+	}
+
 	// ---------------------------------------------------------------------------------------- add neccessary finalizer, if not added
 	// check, wether the globalconfig has the required finalizer or not
 	// if not, then add the finalizer
@@ -94,7 +149,7 @@ func (r *GlobalConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request
 
 	// ---------------------------------------------------------------------------------------- receiving a list of configmaps, which are connected to this specific globalconfig
 	// start the finalizing routine
-	_log.Info("receiving a list of configmaps, which are connected to this specific globalconfig")
+	_log.V(1).Info("receiving a list of configmaps, which are connected to this specific globalconfig")
 
 	var configMapList = &v1.ConfigMapList{}
 	if err := r.List(ctx, configMapList, globalsv1beta2.MatchingLables(gc.UID)); err != nil {
@@ -114,7 +169,9 @@ func (r *GlobalConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request
 			for _, cm := range configMapList.Items {
 
 				_log.Info("removing configmap", "ConfigMap", fmt.Sprintf("[%s/%s]", cm.Namespace, cm.Name))
-				if err := r.Delete(ctx, &cm, &client.DeleteOptions{}); err != nil {
+				if err := recordWrite(ctx, r.Audit, kindGlobalConfig, gc, audit.ActionDelete, cm.Namespace, cm.Annotations[globalsv1beta2.AnnotationContentHash], func(ctx context.Context) error {
+					return r.Delete(ctx, &cm, &client.DeleteOptions{})
+				}); err != nil {
 
 					_log.Error(err, "error removing configmap", fmt.Sprintf("ConfigMap[%s/%s]", cm.Namespace, cm.Name))
 					return ctrl.Result{Requeue: true}, err
@@ -133,100 +190,320 @@ func (r *GlobalConfigReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, nil
 	}
 
+	// ---------------------------------------------------------------------------------------- roll back to a former revision, if requested
+	if revision, ok := gc.Annotations[globalsv1beta2.AnnotationRollbackTo]; ok {
+		_log.Info("rolling back the data", "revision", revision)
+
+		data, _, err := getRevision(ctx, r.Client, gc, revision)
+		switch {
+		case isRevisionNotFound(err):
+			_log.Error(err, "error rolling back, dropping the rollback request")
+		case err != nil:
+			_log.Error(err, "error receiving the revision")
+			return ctrl.Result{Requeue: true}, err
+		default:
+			gc.Spec.Data = data
+		}
+
+		// the update triggers a new reconciliation with the former data
+		delete(gc.Annotations, globalsv1beta2.AnnotationRollbackTo)
+		if err = r.Update(ctx, gc); err != nil {
+			_log.Error(err, "error updating the data")
+			return ctrl.Result{Requeue: true}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
 	// ---------------------------------------------------------------------------------------- start processing the globalconfig
-	_log.Info("calculating the namespaces")
+	_log.V(1).Info("calculating the namespaces")
+	var base = gc.DeepCopy()
 	var matches, avoids []v1.Namespace
 	var err error
 	var cm = &v1.ConfigMap{}
 
-	// calculate the neccessary namespaces
-	if matches, avoids, err = gc.Spec.Namespaces.CalculateNamespaces(_log, ctx, r.Client); err != nil {
+	// calculate the neccessary namespaces, the regexpressions are compiled once per generation,
+	// the patterns of the waves of the rollout strategy are compiled on every reconciliation
+	var nsr *globalsv1beta2.CompiledNamespacesRegex
+	var waves [][]*regexp.Regexp
+	if nsr, err = r.regexCache.get(gc, gc.Spec.Namespaces); err == nil && gc.Spec.RolloutStrategy != nil {
+		waves, err = gc.Spec.RolloutStrategy.Compile()
+	}
+	if err != nil {
+		// an invalid regexpression could target, avoid or stage the wrong namespaces, so no configmaps
+		// are touched, until the spec is fixed, the new generation triggers the next reconciliation
+		_log.Error(err, "invalid namespace regexpressions, refusing to touch the configmaps")
+
+		setSelectorCondition(&gc.Status.Conditions, gc.Generation, err)
+		if err = r.Status().Patch(ctx, gc, client.MergeFrom(base)); err != nil {
+			_log.Error(err, "error updating the status")
+			return ctrl.Result{Requeue: true}, err
+		}
+		return ctrl.Result{}, nil
+	}
+	setSelectorCondition(&gc.Status.Conditions, gc.Generation, nil)
+
+	// the protected namespaces are avoided, unless the global object opts in
+	if allow, aErr := globalsv1beta2.AllowsProtectedNamespaces(gc.Annotations); !allow {
+		if aErr != nil {
+			_log.Error(aErr, "the protected namespaces stay avoided")
+		}
+		nsr = nsr.Protect(r.ProtectedNamespaces)
+	}
+	nsr = nsr.Restrict(r.TargetNamespaces)
+
+	if matches, avoids, err = traceCalculateNamespaces(ctx, _log, r.Client, kindGlobalConfig, req.NamespacedName, nsr); err != nil {
 		_log.Error(err, "error calculating the namespaces")
 		return ctrl.Result{Requeue: true}, err
 	}
 
-	// remove existing configmaps from the avoids
-	_log.Info("removing already existing configmap in namespaces to avoid")
-	for i := range avoids {
-		nsLog := _log.WithValues("current ConfigMap", fmt.Sprintf("[%s/%s]", avoids[i].Name, gc.Name))
+	// the globalreplicationpolicies are evaluated against the matching namespaces, a violating
+	// global object does not touch any configmaps, a changed policy triggers the next reconciliation
+	var violations []string
+	if violations, err = policyViolations(ctx, r.Client, gc, "", matches); err != nil {
+		_log.Error(err, "error evaluating the replication policies")
+		return ctrl.Result{Requeue: true}, err
+	}
+	setPolicyCondition(&gc.Status.Conditions, gc.Generation, violations)
+	if len(violations) > 0 {
+		_log.Info("the global object violates the replication policies, refusing to touch the configmaps", "violations", violations)
+		if err = r.Status().Patch(ctx, gc, client.MergeFrom(base)); err != nil {
+			_log.Error(err, "error updating the status")
+			return ctrl.Result{Requeue: true}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// the content hash is compared against the annotation of the existing configmaps
+	var hash = globalsv1beta2.ContentHash(r.HashKey, gc.Spec.Data)
+	var unkeyed = globalsv1beta2.UnkeyedContentHash(gc.Spec.Data)
 
-		cm = &v1.ConfigMap{}
-		if err = r.Get(ctx, types.NamespacedName{Namespace: avoids[i].Name, Name: gc.Name}, cm, &client.GetOptions{}); err != nil {
-			if errors.IsNotFound(err) {
-				continue
-			}
-			nsLog.Error(err, "error receiving configmapdata")
+	// ---------------------------------------------------------------------------------------- calculate the drift of the existing configmaps
+	var existing = make(map[string]*v1.ConfigMap, len(configMapList.Items))
+	var owned = make(map[string]bool, len(configMapList.Items))
+	for i := range configMapList.Items {
+		existing[configMapList.Items[i].Namespace] = &configMapList.Items[i]
+		owned[configMapList.Items[i].Namespace] = hasContent(&configMapList.Items[i], hash, unkeyed)
+	}
+
+	var plan *globalsv1beta2.ReplicationPlan
+	if plan, err = planChanges(ctx, r.Client, func() client.Object { return &v1.ConfigMap{} }, gc.Name, hash, matches, avoids, owned); err != nil {
+		_log.Error(err, "error calculating the planned changes")
+		return ctrl.Result{Requeue: true}, err
+	}
+	var drifted = driftOf(plan)
+	var conflicts = namespaceSet(plan.Conflicts)
+	if len(conflicts) > 0 {
+		_log.Info("the matching namespaces contain configmaps, which are not owned by the globalconfig", "conflicts", plan.Conflicts)
+	}
+	setConflictCondition(&gc.Status.Conditions, gc.Generation, plan.Conflicts)
+
+	gc.Status.DeployedConfigMaps = make([]globalsv1beta2.DeployedConfigMap, 0, len(matches))
+	for i := range matches {
+		var deployed = globalsv1beta2.DeployedConfigMap{Namespace: matches[i].Name}
+		if current, ok := existing[matches[i].Name]; ok {
+			deployed.ContentHash = current.Annotations[globalsv1beta2.AnnotationContentHash]
+			deployed.InSync = owned[matches[i].Name]
+		}
+		gc.Status.DeployedConfigMaps = append(gc.Status.DeployedConfigMaps, deployed)
+	}
+	sort.Slice(gc.Status.DeployedConfigMaps, func(i, j int) bool {
+		return gc.Status.DeployedConfigMaps[i].Namespace < gc.Status.DeployedConfigMaps[j].Namespace
+	})
+
+	// ---------------------------------------------------------------------------------------- a dry run only reports the planned changes
+	if gc.Spec.DryRun {
+		_log.Info("dry run, only reporting the planned changes", "create", len(plan.Create), "update", len(plan.Update), "delete", len(plan.Delete))
+
+		gc.Status.Plan = plan
+		setDryRunCondition(&gc.Status.Conditions, gc.Generation, plan)
+		setSyncConditions(&gc.Status.Conditions, gc.Generation, gc.Spec.Suspend, drifted)
+		if err = r.Status().Patch(ctx, gc, client.MergeFrom(base)); err != nil {
+			_log.Error(err, "error updating the status")
+			return ctrl.Result{Requeue: true}, err
+		}
+		return ctrl.Result{RequeueAfter: resyncAfter(r.ResyncPeriod, gc.Spec.ResyncInterval)}, nil
+	}
+	gc.Status.Plan = nil
+	setDryRunCondition(&gc.Status.Conditions, gc.Generation, nil)
+
+	// ---------------------------------------------------------------------------------------- a suspended globalconfig does not touch its configmaps
+	if gc.Spec.Suspend {
+		_log.Info("replication is suspended, only reporting the drift", "drifted", drifted)
+
+		setSyncConditions(&gc.Status.Conditions, gc.Generation, true, drifted)
+		if err = r.Status().Patch(ctx, gc, client.MergeFrom(base)); err != nil {
+			_log.Error(err, "error updating the status")
 			return ctrl.Result{Requeue: true}, err
 		}
-		if err = r.Delete(ctx, cm, &client.DeleteOptions{}); err != nil {
+		return ctrl.Result{RequeueAfter: resyncAfter(r.ResyncPeriod, gc.Spec.ResyncInterval)}, nil
+	}
+
+	// ---------------------------------------------------------------------------------------- record the current revision of the data, a suspended object records none, since its data is not replicated
+	if gc.Status.CurrentRevision, err = recordRevision(ctx, r.Client, r.Scheme, gc, gc.Spec.Data, "", gc.Spec.RevisionHistoryLimit); err != nil {
+		_log.Error(err, "error recording the revision")
+		return ctrl.Result{Requeue: true}, err
+	}
+
+	// ---------------------------------------------------------------------------------------- stage the rollout of the outdated configmaps
+	var deferred map[string]bool
+	var requeueAfter time.Duration
+	if gc.Spec.RolloutStrategy != nil {
+		// the conflicts are never updated, so they are not part of the rollout
+		var outdated = append(append([]string{}, plan.Create...), plan.Update...)
+		sort.Strings(outdated)
+
+		if gc.Status.Rollout == nil {
+			gc.Status.Rollout = &globalsv1beta2.RolloutStatus{}
+		}
+		deferred, requeueAfter = stageRollout(gc.Spec.RolloutStrategy, waves, gc.Status.Rollout, hash, outdated, len(matches)-len(conflicts), time.Now())
+		_log.Info("staged the rollout", "wave", gc.Status.Rollout.CurrentWaveName, "deferred", len(deferred))
+	} else {
+		gc.Status.Rollout = nil
+	}
+
+	// remove existing configmaps from the avoids, only the configmaps, which carry the uid of this
+	// globalconfig, are removed, an object with the same name, e.g. a configmap of the system in a
+	// protected namespace, is never touched
+	_log.V(1).Info("removing already existing configmap in namespaces to avoid")
+	for _, ns := range plan.Delete {
+		nsLog := _log.WithValues("current ConfigMap", fmt.Sprintf("[%s/%s]", ns, gc.Name))
+
+		cm = existing[ns]
+		if err = recordWrite(ctx, r.Audit, kindGlobalConfig, gc, audit.ActionDelete, ns, cm.Annotations[globalsv1beta2.AnnotationContentHash], func(ctx context.Context) error {
+			return r.Delete(ctx, cm, &client.DeleteOptions{})
+		}); err != nil {
 			nsLog.Error(err, "error removing configmap")
 			return ctrl.Result{Requeue: true}, err
 		}
 	}
 
-	// create or update the configmaps from the matching namespaces
+	// create or update the configmaps from the matching namespaces, as planned
+	var creates, updates = namespaceSet(plan.Create), namespaceSet(plan.Update)
 	for i := range matches {
 		nsLog := _log.WithValues("current ConfigMap", fmt.Sprintf("[%s/%s]", matches[i].Name, gc.Name))
 
-		cm = &v1.ConfigMap{}
-		if err = r.Get(ctx, types.NamespacedName{Namespace: matches[i].Name, Name: gc.Name}, cm, &client.GetOptions{}); err != nil && !errors.IsNotFound(err) {
-			nsLog.Error(err, "error requesting configmapdata")
-			return ctrl.Result{Requeue: true}, err
+		// outdated namespaces of later rollout steps are skipped
+		if deferred[matches[i].Name] {
+			continue
 		}
 
-		// if the configmap does not exist, then create a new configmap
-		if errors.IsNotFound(err) {
-
+		switch {
+		case creates[matches[i].Name]:
 			nsLog.Info("creating configmap")
 			cm = &v1.ConfigMap{}
 			// create the actual object
 			cm.Name = gc.Name
 			cm.Namespace = matches[i].Name
-			// cm.Annotations = globalsv1beta2.Annotations(gc.ResourceVersion)
+			cm.Annotations = globalsv1beta2.Annotations(hash, gc)
 			cm.Data = gc.Spec.Data
 			cm.Immutable = func() *bool { b := true; return &b }()
-			cm.Labels = globalsv1beta2.MatchingLables(gc.UID)
-			if err = r.Create(ctx, cm, &client.CreateOptions{}); err != nil {
+			cm.Labels = globalsv1beta2.Labels(kindGlobalConfig, gc)
+			if err = recordWrite(ctx, r.Audit, kindGlobalConfig, gc, audit.ActionCreate, matches[i].Name, hash, func(ctx context.Context) error {
+				return r.Create(ctx, cm, &client.CreateOptions{})
+			}); err != nil {
 				nsLog.Error(err, "error creating new configmap")
 				return ctrl.Result{Requeue: true}, err
 			}
-			continue
-		}
 
-		nsLog.Info("updating configmap")
 		// since all configmaps where created with the immutable=true flag, we can not simple update them,
 		// we have to delete the configmap and then create the new configmap
-		if !reflect.DeepEqual(cm.Data, gc.Spec.Data) {
-			if err = r.Delete(ctx, cm, &client.DeleteOptions{}); err != nil {
-				nsLog.Error(err, "error creating new configmap")
+		case updates[matches[i].Name]:
+			nsLog.Info("updating configmap")
+			cm = existing[matches[i].Name]
+
+			if err = recordWrite(ctx, r.Audit, kindGlobalConfig, gc, audit.ActionUpdate, matches[i].Name, hash, func(ctx context.Context) error {
+				if err := r.Delete(ctx, cm, &client.DeleteOptions{}); err != nil {
+					return err
+				}
+
+				cm = &v1.ConfigMap{}
+				// provide data
+				cm.Name = gc.Name
+				cm.Namespace = matches[i].Name
+				cm.Annotations = globalsv1beta2.Annotations(hash, gc)
+				cm.Data = gc.Spec.Data
+				cm.Immutable = func() *bool { b := true; return &b }()
+				cm.Labels = globalsv1beta2.Labels(kindGlobalConfig, gc)
+
+				// recreate the configmap
+				return r.Create(ctx, cm, &client.CreateOptions{})
+			}); err != nil {
+				nsLog.Error(err, "error updating configmap")
 				return ctrl.Result{Requeue: true}, err
 			}
 
-			cm = &v1.ConfigMap{}
-			// provide data
-			cm.Name = gc.Name
-			cm.Namespace = matches[i].Name
-			// cm.Annotations = globalsv1beta2.Annotations(gc.ResourceVersion)
-			cm.Data = gc.Spec.Data
-			cm.Immutable = func() *bool { b := true; return &b }()
-			cm.Labels = globalsv1beta2.MatchingLables(gc.UID)
+		case conflicts[matches[i].Name]:
+			nsLog.V(1).Info("skipping configmap, which is not owned by the globalconfig")
 
-			// recreate the configmap
-			if err = r.Create(ctx, cm, &client.CreateOptions{}); err != nil {
-				nsLog.Error(err, "error creating new configmap")
+		default:
+			// configmaps of former versions do not point to their global object or carry the unkeyed content hash
+			if cm = existing[matches[i].Name]; cm == nil || !needsParentMetadata(cm, hash) {
+				continue
+			}
+			if err = recordWrite(ctx, r.Audit, kindGlobalConfig, gc, audit.ActionPatch, matches[i].Name, hash, func(ctx context.Context) error {
+				return patchParentMetadata(ctx, r.Client, cm, kindGlobalConfig, gc, hash)
+			}); err != nil {
+				nsLog.Error(err, "error labeling configmap")
 				return ctrl.Result{Requeue: true}, err
 			}
 		}
 	}
 
-	return ctrl.Result{
-		RequeueAfter: (3 * time.Minute),
-	}, nil
+	// ---------------------------------------------------------------------------------------- restart the workloads, which consume the configmaps
+	if gc.Spec.Rollout && !r.DisableWorkloadRollout {
+		_log.Info("rolling out the configmap to the consuming workloads")
+		for i := range matches {
+			nsLog := _log.WithValues("current ConfigMap", fmt.Sprintf("[%s/%s]", matches[i].Name, gc.Name))
+
+			if deferred[matches[i].Name] || conflicts[matches[i].Name] {
+				continue
+			}
+
+			if err = rolloutWorkloads(ctx, r.Client, nsLog, r.Audit, audit.ObjectOf(kindGlobalConfig, gc), matches[i].Name, kindConfigMap, gc.Name, hash, unkeyed, creates[matches[i].Name] || updates[matches[i].Name]); err != nil {
+				nsLog.Error(err, "error restarting the consuming workloads")
+				return ctrl.Result{Requeue: true}, err
+			}
+		}
+	}
+
+	// ---------------------------------------------------------------------------------------- update the status
+	for i := range gc.Status.DeployedConfigMaps {
+		if deferred[gc.Status.DeployedConfigMaps[i].Namespace] || conflicts[gc.Status.DeployedConfigMaps[i].Namespace] {
+			continue
+		}
+		gc.Status.DeployedConfigMaps[i].ContentHash = hash
+		gc.Status.DeployedConfigMaps[i].InSync = true
+	}
+	setSyncConditions(&gc.Status.Conditions, gc.Generation, false, len(deferred)+len(conflicts))
+	if len(deferred) > 0 {
+		setRolloutCondition(&gc.Status.Conditions, gc.Generation, gc.Status.Rollout)
+	}
+	if err = r.Status().Patch(ctx, gc, client.MergeFrom(base)); err != nil {
+		_log.Error(err, "error updating the status")
+		return ctrl.Result{Requeue: true}, err
+	}
+
+	// continue with the next step of the staged rollout
+	if len(deferred) > 0 {
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+
+	return ctrl.Result{RequeueAfter: resyncAfter(r.ResyncPeriod, gc.Spec.ResyncInterval)}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *GlobalConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	var newList = func() client.ObjectList { return &globalsv1beta2.GlobalConfigList{} }
+
+	var b = ctrl.NewControllerManagedBy(mgr).
+		WithOptions(r.Options).
 		For(&globalsv1beta2.GlobalConfig{}).
-		Complete(r)
+		Watches(&source.Kind{Type: &v1.ConfigMap{}}, enqueueParent(kindGlobalConfig)).
+		Watches(&source.Kind{Type: &globalsv1beta2.GlobalReplicationPolicy{}}, enqueueAll(mgr.GetClient(), newList))
+
+	// the global objects of the claimed shards are reconciled, after the shards changed
+	if r.Sharder != nil {
+		b = b.Watches(&source.Channel{Source: r.Sharder.Subscribe()}, enqueueAll(mgr.GetClient(), newList))
+	}
+	return b.Complete(r)
 }
@@ -20,24 +20,61 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
-	"reflect"
+	"regexp"
+	"sort"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	globalsv1beta2 "github.com/jnnkrdb/configrdb/api/v1beta2"
+	"github.com/jnnkrdb/configrdb/internal/audit"
+	"github.com/jnnkrdb/configrdb/internal/sharding"
 )
 
 // GlobalSecretReconciler reconciles a GlobalSecret object
 type GlobalSecretReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// the namespaces, which are always avoided, unless the global object
+	// opts in with the annotation [AnnotationAllowProtectedNamespaces]
+	ProtectedNamespaces []string
+
+	// the namespaces, whose global objects are reconciled, all namespaces if empty
+	WatchNamespaces []string
+
+	// the only namespaces, which are replicated into, all namespaces if empty
+	TargetNamespaces []string
+
+	// the audit of the changes to the replicated objects and the workloads, nil records nothing
+	Audit *audit.Auditor
+
+	// the key of the content hashes, see ContentHash
+	HashKey []byte
+
+	// the shards of this replica, nil reconciles all global objects
+	Sharder *sharding.Sharder
+
+	// the interval, in which the global objects are reconciled again without an
+	// event, unless they set spec.resyncInterval, 0 disables the periodic reconciliation
+	ResyncPeriod time.Duration
+
+	// never restart the workloads, even if the global objects set spec.rollout
+	DisableWorkloadRollout bool
+
+	// the options of the controller, e.g. the number of concurrent reconciles and the rate limiter
+	Options controller.Options
+
+	// the compiled namespace regexpressions of the reconciled objects
+	regexCache namespacesRegexCache
 }
 
 //+kubebuilder:rbac:groups=globals.jnnkrdb.de,resources=globalsecrets,verbs=get;list;watch;create;update;patch;delete
@@ -56,8 +93,23 @@ type GlobalSecretReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.14.1/pkg/reconcile
 func (r *GlobalSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	return traceReconcile(ctx, kindGlobalSecret, req, func(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+		var rd = &redactor{}
+		res, err := r.reconcile(ctx, req, rd)
+		return res, rd.redactError(err)
+	})
+}
+
+// reconcile a global object, the reconciliation is traced by [Reconcile] and the
+// returned error is redacted with the values of the global secret
+func (r *GlobalSecretReconciler) reconcile(ctx context.Context, req ctrl.Request, rd *redactor) (ctrl.Result, error) {
 	var _log = log.FromContext(ctx).WithName(fmt.Sprintf("GlobalSecret [%s]", req.NamespacedName))
-	_log.Info("start reconciling")
+	_log.V(1).Info("start reconciling")
+
+	// the cache can contain the global objects of the target namespaces, which are not watched
+	if !inNamespaces(r.WatchNamespaces, req.Namespace) {
+		return ctrl.Result{}, nil
+	}
 
 	// ---------------------------------------------------------------------------------------- get the current globalconfig from the reconcile request
 	// create caching object
@@ -66,22 +118,32 @@ func (r *GlobalSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request
 	// parse the ctrl.Request into a globalsecret
 	if err := r.Get(ctx, req.NamespacedName, gs); err != nil {
 
-		_log.Error(err, "error reconciling globalsecret")
-
 		// if the error is an "NotFound" error, then the globalsecret probably was deleted
 		// returning no error
 		if errors.IsNotFound(err) {
+			_log.V(1).Info("the globalsecret was deleted")
+			r.regexCache.forget(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
 
 		// if the error is something else, return the error
+		_log.Error(err, "error reconciling globalsecret")
 		return ctrl.Result{}, err
 	}
 
+	// the values of the secret are redacted from the logs and the returned errors
+	rd.add(gs.Spec.Data)
+	_log = objectLogger(_log, gs.Annotations, rd)
+
+	// the global objects of the other shards are reconciled by the other replicas
+	if r.Sharder != nil && !r.Sharder.Owns(gs.UID) {
+		return ctrl.Result{}, nil
+	}
+
 	// ---------------------------------------------------------------------------------------- add neccessary finalizer, if not added
 	// check, wether the globalsecret has the required finalizer or not
 	// if not, then add the finalizer
-	if controllerutil.ContainsFinalizer(gs, globalsv1beta2.FinalizerGlobal) {
+	if !controllerutil.ContainsFinalizer(gs, globalsv1beta2.FinalizerGlobal) {
 		_log.Info("appending finalizer")
 
 		// add the desired finalizer and update the object
@@ -94,7 +156,7 @@ func (r *GlobalSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request
 
 	// ---------------------------------------------------------------------------------------- receiving a list of secrets, which are connected to this specific globalsecret
 	// start the finalizing routine
-	_log.Info("receiving a list of secrets, which are connected to this specific globalsecret")
+	_log.V(1).Info("receiving a list of secrets, which are connected to this specific globalsecret")
 
 	var secretList = &v1.SecretList{}
 	if err := r.List(ctx, secretList, globalsv1beta2.MatchingLables(gs.UID)); err != nil {
@@ -114,7 +176,9 @@ func (r *GlobalSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request
 			for _, scrt := range secretList.Items {
 
 				_log.Info("removing secret", "Secret", fmt.Sprintf("[%s/%s]", scrt.Namespace, scrt.Name))
-				if err := r.Delete(ctx, &scrt, &client.DeleteOptions{}); err != nil {
+				if err := recordWrite(ctx, r.Audit, kindGlobalSecret, gs, audit.ActionDelete, scrt.Namespace, scrt.Annotations[globalsv1beta2.AnnotationContentHash], func(ctx context.Context) error {
+					return rd.redactError(r.Delete(ctx, &scrt, &client.DeleteOptions{}))
+				}); err != nil {
 
 					_log.Error(err, "error removing secret", "Secret", fmt.Sprintf("[%s/%s]", scrt.Namespace, scrt.Name))
 					return ctrl.Result{Requeue: true}, err
@@ -132,118 +196,335 @@ func (r *GlobalSecretReconciler) Reconcile(ctx context.Context, req ctrl.Request
 		return ctrl.Result{}, nil
 	}
 
+	// ---------------------------------------------------------------------------------------- roll back to a former revision, if requested
+	if revision, ok := gs.Annotations[globalsv1beta2.AnnotationRollbackTo]; ok {
+		_log.Info("rolling back the data", "revision", revision)
+
+		data, secretType, err := getRevision(ctx, r.Client, gs, revision)
+		switch {
+		case isRevisionNotFound(err):
+			_log.Error(err, "error rolling back, dropping the rollback request")
+		case err != nil:
+			_log.Error(err, "error receiving the revision")
+			return ctrl.Result{Requeue: true}, err
+		default:
+			gs.Spec.Data = data
+			gs.Spec.Type = secretType
+		}
+
+		// the update triggers a new reconciliation with the former data
+		delete(gs.Annotations, globalsv1beta2.AnnotationRollbackTo)
+		if err = r.Update(ctx, gs); err != nil {
+			_log.Error(err, "error updating the data")
+			return ctrl.Result{Requeue: true}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
 	// ---------------------------------------------------------------------------------------- start processing the globalsecret
-	_log.Info("calculating the namespaces")
+	_log.V(1).Info("calculating the namespaces")
+	var base = gs.DeepCopy()
 	var matches, avoids []v1.Namespace
 	var err error
 	var scrt = &v1.Secret{}
 
-	// calculate the neccessary namespaces
-	if matches, avoids, err = gs.Spec.Namespaces.CalculateNamespaces(_log, ctx, r.Client); err != nil {
+	// calculate the neccessary namespaces, the regexpressions are compiled once per generation,
+	// the patterns of the waves of the rollout strategy are compiled on every reconciliation
+	var nsr *globalsv1beta2.CompiledNamespacesRegex
+	var waves [][]*regexp.Regexp
+	if nsr, err = r.regexCache.get(gs, gs.Spec.Namespaces); err == nil && gs.Spec.RolloutStrategy != nil {
+		waves, err = gs.Spec.RolloutStrategy.Compile()
+	}
+	if err != nil {
+		// an invalid regexpression could target, avoid or stage the wrong namespaces, so no secrets
+		// are touched, until the spec is fixed, the new generation triggers the next reconciliation
+		_log.Error(err, "invalid namespace regexpressions, refusing to touch the secrets")
+
+		setSelectorCondition(&gs.Status.Conditions, gs.Generation, err)
+		if err = r.Status().Patch(ctx, gs, client.MergeFrom(base)); err != nil {
+			_log.Error(err, "error updating the status")
+			return ctrl.Result{Requeue: true}, err
+		}
+		return ctrl.Result{}, nil
+	}
+	setSelectorCondition(&gs.Status.Conditions, gs.Generation, nil)
+
+	// the protected namespaces are avoided, unless the global object opts in
+	if allow, aErr := globalsv1beta2.AllowsProtectedNamespaces(gs.Annotations); !allow {
+		if aErr != nil {
+			_log.Error(aErr, "the protected namespaces stay avoided")
+		}
+		nsr = nsr.Protect(r.ProtectedNamespaces)
+	}
+	nsr = nsr.Restrict(r.TargetNamespaces)
+
+	if matches, avoids, err = traceCalculateNamespaces(ctx, _log, r.Client, kindGlobalSecret, req.NamespacedName, nsr); err != nil {
 		_log.Error(err, "error calculating the namespaces")
 		return ctrl.Result{Requeue: true}, err
 	}
 
-	// remove existing secrets from the avoids
-	_log.Info("removing already existing secrets in namespaces to avoid")
-	for i := range avoids {
-		nsLog := _log.WithValues("current Secret", fmt.Sprintf("[%s/%s]", avoids[i].Name, gs.Name))
+	// the globalreplicationpolicies are evaluated against the matching namespaces, a violating
+	// global object does not touch any secrets, a changed policy triggers the next reconciliation
+	var violations []string
+	if violations, err = policyViolations(ctx, r.Client, gs, gs.Spec.Type, matches); err != nil {
+		_log.Error(err, "error evaluating the replication policies")
+		return ctrl.Result{Requeue: true}, err
+	}
+	setPolicyCondition(&gs.Status.Conditions, gs.Generation, violations)
+	if len(violations) > 0 {
+		_log.Info("the global object violates the replication policies, refusing to touch the secrets", "violations", violations)
+		if err = r.Status().Patch(ctx, gs, client.MergeFrom(base)); err != nil {
+			_log.Error(err, "error updating the status")
+			return ctrl.Result{Requeue: true}, err
+		}
+		return ctrl.Result{}, nil
+	}
 
-		scrt = &v1.Secret{}
-		if err = r.Get(ctx, types.NamespacedName{Namespace: avoids[i].Name, Name: gs.Name}, scrt, &client.GetOptions{}); err != nil {
-			if errors.IsNotFound(err) {
-				continue
-			}
-			nsLog.Error(err, "error receiving secretdata")
+	// the content hash is compared against the annotation of the existing secrets
+	var hash = globalsv1beta2.ContentHash(r.HashKey, gs.Spec.Data)
+	var unkeyed = globalsv1beta2.UnkeyedContentHash(gs.Spec.Data)
+
+	// ---------------------------------------------------------------------------------------- calculate the drift of the existing secrets
+	var existing = make(map[string]*v1.Secret, len(secretList.Items))
+	var owned = make(map[string]bool, len(secretList.Items))
+	for i := range secretList.Items {
+		existing[secretList.Items[i].Namespace] = &secretList.Items[i]
+		owned[secretList.Items[i].Namespace] = hasContent(&secretList.Items[i], hash, unkeyed) &&
+			secretList.Items[i].Type == v1.SecretType(gs.Spec.Type)
+	}
+
+	var plan *globalsv1beta2.ReplicationPlan
+	if plan, err = planChanges(ctx, r.Client, func() client.Object { return &v1.Secret{} }, gs.Name, hash, matches, avoids, owned); err != nil {
+		_log.Error(err, "error calculating the planned changes")
+		return ctrl.Result{Requeue: true}, err
+	}
+	var drifted = driftOf(plan)
+	var conflicts = namespaceSet(plan.Conflicts)
+	if len(conflicts) > 0 {
+		_log.Info("the matching namespaces contain secrets, which are not owned by the globalsecret", "conflicts", plan.Conflicts)
+	}
+	setConflictCondition(&gs.Status.Conditions, gs.Generation, plan.Conflicts)
+
+	gs.Status.DeployedSecrets = make([]globalsv1beta2.DeployedSecret, 0, len(matches))
+	for i := range matches {
+		var deployed = globalsv1beta2.DeployedSecret{Namespace: matches[i].Name}
+		if current, ok := existing[matches[i].Name]; ok {
+			deployed.ContentHash = current.Annotations[globalsv1beta2.AnnotationContentHash]
+			deployed.InSync = owned[matches[i].Name]
+		}
+		gs.Status.DeployedSecrets = append(gs.Status.DeployedSecrets, deployed)
+	}
+	sort.Slice(gs.Status.DeployedSecrets, func(i, j int) bool {
+		return gs.Status.DeployedSecrets[i].Namespace < gs.Status.DeployedSecrets[j].Namespace
+	})
+
+	// ---------------------------------------------------------------------------------------- a dry run only reports the planned changes
+	if gs.Spec.DryRun {
+		_log.Info("dry run, only reporting the planned changes", "create", len(plan.Create), "update", len(plan.Update), "delete", len(plan.Delete))
+
+		gs.Status.Plan = plan
+		setDryRunCondition(&gs.Status.Conditions, gs.Generation, plan)
+		setSyncConditions(&gs.Status.Conditions, gs.Generation, gs.Spec.Suspend, drifted)
+		if err = r.Status().Patch(ctx, gs, client.MergeFrom(base)); err != nil {
+			_log.Error(err, "error updating the status")
 			return ctrl.Result{Requeue: true}, err
 		}
-		if err = r.Delete(ctx, scrt, &client.DeleteOptions{}); err != nil {
+		return ctrl.Result{RequeueAfter: resyncAfter(r.ResyncPeriod, gs.Spec.ResyncInterval)}, nil
+	}
+	gs.Status.Plan = nil
+	setDryRunCondition(&gs.Status.Conditions, gs.Generation, nil)
+
+	// ---------------------------------------------------------------------------------------- a suspended globalsecret does not touch its secrets
+	if gs.Spec.Suspend {
+		_log.Info("replication is suspended, only reporting the drift", "drifted", drifted)
+
+		setSyncConditions(&gs.Status.Conditions, gs.Generation, true, drifted)
+		if err = r.Status().Patch(ctx, gs, client.MergeFrom(base)); err != nil {
+			_log.Error(err, "error updating the status")
+			return ctrl.Result{Requeue: true}, err
+		}
+		return ctrl.Result{RequeueAfter: resyncAfter(r.ResyncPeriod, gs.Spec.ResyncInterval)}, nil
+	}
+
+	// ---------------------------------------------------------------------------------------- record the current revision of the data, a suspended object records none, since its data is not replicated
+	if gs.Status.CurrentRevision, err = recordRevision(ctx, r.Client, r.Scheme, gs, gs.Spec.Data, gs.Spec.Type, gs.Spec.RevisionHistoryLimit); err != nil {
+		_log.Error(err, "error recording the revision")
+		return ctrl.Result{Requeue: true}, err
+	}
+
+	// ---------------------------------------------------------------------------------------- stage the rollout of the outdated secrets
+	var deferred map[string]bool
+	var requeueAfter time.Duration
+	if gs.Spec.RolloutStrategy != nil {
+		// the conflicts are never updated, so they are not part of the rollout
+		var outdated = append(append([]string{}, plan.Create...), plan.Update...)
+		sort.Strings(outdated)
+
+		if gs.Status.Rollout == nil {
+			gs.Status.Rollout = &globalsv1beta2.RolloutStatus{}
+		}
+		deferred, requeueAfter = stageRollout(gs.Spec.RolloutStrategy, waves, gs.Status.Rollout, hash, outdated, len(matches)-len(conflicts), time.Now())
+		_log.Info("staged the rollout", "wave", gs.Status.Rollout.CurrentWaveName, "deferred", len(deferred))
+	} else {
+		gs.Status.Rollout = nil
+	}
+
+	// remove existing secrets from the avoids, only the secrets, which carry the uid of this
+	// globalsecret, are removed, an object with the same name, e.g. a secret of the system in a
+	// protected namespace, is never touched
+	_log.V(1).Info("removing already existing secrets in namespaces to avoid")
+	for _, ns := range plan.Delete {
+		nsLog := _log.WithValues("current Secret", fmt.Sprintf("[%s/%s]", ns, gs.Name))
+
+		scrt = existing[ns]
+		if err = recordWrite(ctx, r.Audit, kindGlobalSecret, gs, audit.ActionDelete, ns, scrt.Annotations[globalsv1beta2.AnnotationContentHash], func(ctx context.Context) error {
+			return rd.redactError(r.Delete(ctx, scrt, &client.DeleteOptions{}))
+		}); err != nil {
 			nsLog.Error(err, "error removing secret")
 			return ctrl.Result{Requeue: true}, err
 		}
 	}
 
-	// create or update the secrets from the matching namespaces
+	// decode the base64 data once, before the secrets are created
+	var data = make(map[string]string, len(gs.Spec.Data))
+	for k, v := range gs.Spec.Data {
+		if unenc, err := base64.StdEncoding.DecodeString(v); err != nil {
+			_log.Error(err, "error converting base64 data into secret data bytes", "key", k)
+			return ctrl.Result{Requeue: true}, err
+		} else {
+			data[k] = string(unenc)
+		}
+	}
+
+	// create or update the secrets from the matching namespaces, as planned
+	var creates, updates = namespaceSet(plan.Create), namespaceSet(plan.Update)
 	for i := range matches {
 		nsLog := _log.WithValues("current Secret", fmt.Sprintf("[%s/%s]", matches[i].Name, gs.Name))
 
-		scrt = &v1.Secret{}
-		if err = r.Get(ctx, types.NamespacedName{Namespace: matches[i].Name, Name: gs.Name}, scrt, &client.GetOptions{}); err != nil && !errors.IsNotFound(err) {
-			nsLog.Error(err, "error requesting secretdata")
-			return ctrl.Result{Requeue: true}, err
+		// outdated namespaces of later rollout steps are skipped
+		if deferred[matches[i].Name] {
+			continue
 		}
 
-		// if the configmap does not exist, then create a new configmap
-		if errors.IsNotFound(err) {
-
+		switch {
+		case creates[matches[i].Name]:
 			nsLog.Info("creating secret")
 			scrt = &v1.Secret{}
 			// create the actual object
 			scrt.Name = gs.Name
 			scrt.Namespace = matches[i].Name
-			// cm.Annotations = globalsv1beta2.Annotations(gs.ResourceVersion)
-			data := make(map[string]string)
-			for k, v := range gs.Spec.Data {
-				if unenc, err := base64.StdEncoding.DecodeString(v); err != nil {
-					nsLog.Error(err, "error converting base64 data into secret data bytes")
-					return ctrl.Result{Requeue: true}, err
-				} else {
-					data[k] = string(unenc)
-				}
-			}
+			scrt.Annotations = globalsv1beta2.Annotations(hash, gs)
 			scrt.StringData = data
 			scrt.Type = v1.SecretType(gs.Spec.Type)
 			scrt.Immutable = func() *bool { b := true; return &b }()
-			scrt.Labels = globalsv1beta2.MatchingLables(gs.UID)
-			if err = r.Create(ctx, scrt, &client.CreateOptions{}); err != nil {
+			scrt.Labels = globalsv1beta2.Labels(kindGlobalSecret, gs)
+			if err = recordWrite(ctx, r.Audit, kindGlobalSecret, gs, audit.ActionCreate, matches[i].Name, hash, func(ctx context.Context) error {
+				return rd.redactError(r.Create(ctx, scrt, &client.CreateOptions{}))
+			}); err != nil {
 				nsLog.Error(err, "error creating new secret")
 				return ctrl.Result{Requeue: true}, err
 			}
-			continue
-		}
 
-		nsLog.Info("updating secret")
-		var data = make(map[string]string, len(gs.Spec.Data))
-		for k, v := range gs.Spec.Data {
-			if unenc, err := base64.StdEncoding.DecodeString(v); err != nil {
-				nsLog.Error(err, "error converting base64 data into secret data bytes")
+		// since all secrets where created with the immutable=true flag, we can not simple update them,
+		// we have to delete the secret and then create the new secret
+		case updates[matches[i].Name]:
+			nsLog.Info("updating secret")
+			scrt = existing[matches[i].Name]
+
+			if err = recordWrite(ctx, r.Audit, kindGlobalSecret, gs, audit.ActionUpdate, matches[i].Name, hash, func(ctx context.Context) error {
+				if err := r.Delete(ctx, scrt, &client.DeleteOptions{}); err != nil {
+					return rd.redactError(err)
+				}
+
+				scrt = &v1.Secret{}
+				// provide data
+				scrt.Name = gs.Name
+				scrt.Namespace = matches[i].Name
+				scrt.Annotations = globalsv1beta2.Annotations(hash, gs)
+				scrt.Type = v1.SecretType(gs.Spec.Type)
+				scrt.StringData = data
+				scrt.Immutable = func() *bool { b := true; return &b }()
+				scrt.Labels = globalsv1beta2.Labels(kindGlobalSecret, gs)
+
+				// recreate the secret
+				return rd.redactError(r.Create(ctx, scrt, &client.CreateOptions{}))
+			}); err != nil {
+				nsLog.Error(err, "error updating secret")
 				return ctrl.Result{Requeue: true}, err
-			} else {
-				data[k] = string(unenc)
 			}
-		}
 
-		if !reflect.DeepEqual(data, scrt.StringData) {
+		case conflicts[matches[i].Name]:
+			nsLog.V(1).Info("skipping secret, which is not owned by the globalsecret")
 
-			if err = r.Delete(ctx, scrt, &client.DeleteOptions{}); err != nil {
-				nsLog.Error(err, "error creating new secret")
+		default:
+			// secrets of former versions do not point to their global object or carry the unkeyed content hash
+			if scrt = existing[matches[i].Name]; scrt == nil || !needsParentMetadata(scrt, hash) {
+				continue
+			}
+			if err = recordWrite(ctx, r.Audit, kindGlobalSecret, gs, audit.ActionPatch, matches[i].Name, hash, func(ctx context.Context) error {
+				return rd.redactError(patchParentMetadata(ctx, r.Client, scrt, kindGlobalSecret, gs, hash))
+			}); err != nil {
+				nsLog.Error(err, "error labeling secret")
 				return ctrl.Result{Requeue: true}, err
 			}
+		}
+	}
 
-			scrt = &v1.Secret{}
-			// provide data
-			scrt.Name = gs.Name
-			scrt.Namespace = matches[i].Name
-			// cm.Annotations = globalsv1beta2.Annotations(gs.ResourceVersion)
-			scrt.Type = v1.SecretType(gs.Spec.Type)
-			scrt.StringData = data
-			scrt.Immutable = func() *bool { b := true; return &b }()
-			scrt.Labels = globalsv1beta2.MatchingLables(gs.UID)
+	// ---------------------------------------------------------------------------------------- restart the workloads, which consume the secrets
+	if gs.Spec.Rollout && !r.DisableWorkloadRollout {
+		_log.Info("rolling out the secret to the consuming workloads")
+		for i := range matches {
+			nsLog := _log.WithValues("current Secret", fmt.Sprintf("[%s/%s]", matches[i].Name, gs.Name))
 
-			// recreate the configmap
-			if err = r.Create(ctx, scrt, &client.CreateOptions{}); err != nil {
-				nsLog.Error(err, "error creating new secret")
+			if deferred[matches[i].Name] || conflicts[matches[i].Name] {
+				continue
+			}
+
+			if err = rolloutWorkloads(ctx, r.Client, nsLog, r.Audit, audit.ObjectOf(kindGlobalSecret, gs), matches[i].Name, kindSecret, gs.Name, hash, unkeyed, creates[matches[i].Name] || updates[matches[i].Name]); err != nil {
+				nsLog.Error(err, "error restarting the consuming workloads")
 				return ctrl.Result{Requeue: true}, err
 			}
 		}
 	}
 
-	return ctrl.Result{}, nil
+	// ---------------------------------------------------------------------------------------- update the status
+	for i := range gs.Status.DeployedSecrets {
+		if deferred[gs.Status.DeployedSecrets[i].Namespace] || conflicts[gs.Status.DeployedSecrets[i].Namespace] {
+			continue
+		}
+		gs.Status.DeployedSecrets[i].ContentHash = hash
+		gs.Status.DeployedSecrets[i].InSync = true
+	}
+	setSyncConditions(&gs.Status.Conditions, gs.Generation, false, len(deferred)+len(conflicts))
+	if len(deferred) > 0 {
+		setRolloutCondition(&gs.Status.Conditions, gs.Generation, gs.Status.Rollout)
+	}
+	if err = r.Status().Patch(ctx, gs, client.MergeFrom(base)); err != nil {
+		_log.Error(err, "error updating the status")
+		return ctrl.Result{Requeue: true}, err
+	}
+
+	// continue with the next step of the staged rollout
+	if len(deferred) > 0 {
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+
+	return ctrl.Result{RequeueAfter: resyncAfter(r.ResyncPeriod, gs.Spec.ResyncInterval)}, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *GlobalSecretReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	var newList = func() client.ObjectList { return &globalsv1beta2.GlobalSecretList{} }
+
+	var b = ctrl.NewControllerManagedBy(mgr).
+		WithOptions(r.Options).
 		For(&globalsv1beta2.GlobalSecret{}).
-		Complete(r)
+		Watches(&source.Kind{Type: &v1.Secret{}}, enqueueParent(kindGlobalSecret)).
+		Watches(&source.Kind{Type: &globalsv1beta2.GlobalReplicationPolicy{}}, enqueueAll(mgr.GetClient(), newList))
+
+	// the global objects of the claimed shards are reconciled, after the shards changed
+	if r.Sharder != nil {
+		b = b.Watches(&source.Channel{Source: r.Sharder.Subscribe()}, enqueueAll(mgr.GetClient(), newList))
+	}
+	return b.Complete(r)
 }
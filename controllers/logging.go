@@ -0,0 +1,231 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/base64"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	globalsv1beta2 "github.com/jnnkrdb/configrdb/api/v1beta2"
+)
+
+// the fields of the logged key value pairs, whose values are always redacted
+var redactedFields = map[string]bool{"data": true, "stringData": true}
+
+// the redactor removes the values of a global secret from the logs, the values are
+// redacted in their base64 encoded and in their decoded form, so the errors of the
+// api server can not leak them
+//
+// the logged key value pairs are redacted by their key, if the key is one of the keys
+// of the data or a field like "data", and by their value, if the value is one of the
+// values, the messages and the errors are searched for every value, which is not one
+// of the keys of the data as well, so the messages about a key stay readable
+//
+// the zero value does not redact anything
+type redactor struct {
+	keys   map[string]bool
+	exact  map[string]bool
+	values []string
+}
+
+// add the data of a global secret to the redacted keys and values
+func (rd *redactor) add(data map[string]string) {
+	if rd.keys == nil {
+		rd.keys, rd.exact = make(map[string]bool, len(data)), make(map[string]bool, 2*len(data))
+	}
+	for k, v := range data {
+		rd.keys[k] = true
+
+		var forms = []string{v}
+		if decoded, err := base64.StdEncoding.DecodeString(v); err == nil {
+			forms = append(forms, string(decoded))
+		}
+		for _, form := range forms {
+			if form == "" || rd.exact[form] {
+				continue
+			}
+			rd.exact[form] = true
+			rd.values = append(rd.values, form)
+		}
+	}
+}
+
+// check whether the redactor redacts anything
+func (rd *redactor) enabled() bool {
+	return rd != nil && (len(rd.keys) > 0 || len(rd.exact) > 0)
+}
+
+// redact a string, every occurrence of a value is replaced, the occurrences of
+// overlapping values are merged, so no part of either value is left
+func (rd *redactor) redact(s string) string {
+	if !rd.enabled() || len(rd.values) == 0 {
+		return s
+	}
+
+	// mark the bytes, which belong to any value
+	var marked []bool
+	for _, v := range rd.values {
+		if rd.keys[v] {
+			continue
+		}
+		for offset := 0; offset < len(s); {
+			var i = strings.Index(s[offset:], v)
+			if i < 0 {
+				break
+			}
+			if marked == nil {
+				marked = make([]bool, len(s))
+			}
+			for j := offset + i; j < offset+i+len(v); j++ {
+				marked[j] = true
+			}
+			offset += i + 1
+		}
+	}
+	if marked == nil {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch {
+		case !marked[i]:
+			b.WriteByte(s[i])
+		case i == 0 || !marked[i-1]:
+			b.WriteString(globalsv1beta2.Redacted)
+		}
+	}
+	return b.String()
+}
+
+// redact the message of an error, the redacted error still unwraps to the
+// original error, so errors.Is and errors.As keep working
+func (rd *redactor) redactError(err error) error {
+	if err == nil || !rd.enabled() {
+		return err
+	}
+	return &redactedError{err: err, msg: rd.redact(err.Error())}
+}
+
+// redact the key value pairs of a log call, see redactor
+func (rd *redactor) redactValues(keysAndValues []interface{}) []interface{} {
+	if !rd.enabled() {
+		return keysAndValues
+	}
+	var redacted = make([]interface{}, len(keysAndValues))
+	for i, v := range keysAndValues {
+		if i%2 == 1 {
+			if key, ok := keysAndValues[i-1].(string); ok && (rd.keys[key] || redactedFields[key]) {
+				redacted[i] = globalsv1beta2.Redacted
+				continue
+			}
+		}
+		switch v := v.(type) {
+		case string:
+			if rd.exact[v] {
+				redacted[i] = globalsv1beta2.Redacted
+			} else {
+				redacted[i] = rd.redact(v)
+			}
+		case error:
+			redacted[i] = rd.redactError(v)
+		default:
+			redacted[i] = v
+		}
+	}
+	return redacted
+}
+
+// an error, whose message is redacted
+type redactedError struct {
+	err error
+	msg string
+}
+
+func (e *redactedError) Error() string { return e.msg }
+func (e *redactedError) Unwrap() error { return e.err }
+
+// get the logger of a global object
+//
+// the verbosity, which is requested with the annotation [AnnotationLogVerbosity],
+// is subtracted from the level of every log, so the logs of this object are
+// written, even if the log level of the manager is lower, and the values of the
+// redactor are removed from all messages, values and errors
+func objectLogger(l logr.Logger, annotations map[string]string, rd *redactor) logr.Logger {
+	var sink = l.GetSink()
+	if sink == nil {
+		return l
+	}
+
+	verbosity, err := globalsv1beta2.LogVerbosity(annotations)
+	if err != nil {
+		l.Error(err, "ignoring the requested verbosity")
+	}
+
+	// the wrapping sink adds a frame, which must be skipped by the caller information
+	if cd, ok := sink.(logr.CallDepthLogSink); ok {
+		sink = cd.WithCallDepth(1)
+	}
+	return l.WithSink(&objectSink{sink: sink, verbosity: verbosity, redactor: rd})
+}
+
+// the log sink of a global object, see objectLogger
+type objectSink struct {
+	sink      logr.LogSink
+	verbosity int
+	redactor  *redactor
+}
+
+func (s *objectSink) level(level int) int {
+	if level -= s.verbosity; level < 0 {
+		return 0
+	}
+	return level
+}
+
+func (s *objectSink) Init(info logr.RuntimeInfo) {
+	s.sink.Init(info)
+}
+
+func (s *objectSink) Enabled(level int) bool {
+	return s.sink.Enabled(s.level(level))
+}
+
+func (s *objectSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	s.sink.Info(s.level(level), s.redactor.redact(msg), s.redactor.redactValues(keysAndValues)...)
+}
+
+func (s *objectSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.sink.Error(s.redactor.redactError(err), s.redactor.redact(msg), s.redactor.redactValues(keysAndValues)...)
+}
+
+func (s *objectSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	return &objectSink{sink: s.sink.WithValues(s.redactor.redactValues(keysAndValues)...), verbosity: s.verbosity, redactor: s.redactor}
+}
+
+func (s *objectSink) WithName(name string) logr.LogSink {
+	return &objectSink{sink: s.sink.WithName(name), verbosity: s.verbosity, redactor: s.redactor}
+}
+
+func (s *objectSink) WithCallDepth(depth int) logr.LogSink {
+	if cd, ok := s.sink.(logr.CallDepthLogSink); ok {
+		return &objectSink{sink: cd.WithCallDepth(depth), verbosity: s.verbosity, redactor: s.redactor}
+	}
+	return s
+}
@@ -0,0 +1,118 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	globalsv1beta2 "github.com/jnnkrdb/configrdb/api/v1beta2"
+)
+
+func TestObjectLogger(t *testing.T) {
+	const decoded = "hunter2-password"
+	var encoded = base64.StdEncoding.EncodeToString([]byte(decoded))
+
+	var rd = &redactor{}
+	rd.add(map[string]string{"password": encoded})
+
+	var logged []string
+	var base = funcr.New(func(prefix, args string) { logged = append(logged, args) }, funcr.Options{Verbosity: 0})
+
+	// the error of the api server contains the decoded value
+	var apiErr = apierrors.NewInvalid(schema.GroupKind{Kind: "Secret"}, "gs", nil)
+	apiErr.ErrStatus.Message = "the value " + decoded + " is invalid"
+
+	var l = objectLogger(base, map[string]string{globalsv1beta2.AnnotationLogVerbosity: "1"}, rd)
+	l.V(1).Info("creating secret "+encoded, "value", decoded)
+	l.WithValues("data", encoded).Error(apiErr, "error creating new secret")
+	l.V(2).Info("not logged")
+
+	if len(logged) != 2 {
+		t.Fatalf("expected the V(1) log and the error, got %v", logged)
+	}
+	for _, out := range logged {
+		if strings.Contains(out, decoded) || strings.Contains(out, encoded) {
+			t.Errorf("the value was not redacted: %s", out)
+		}
+		if !strings.Contains(out, globalsv1beta2.Redacted) {
+			t.Errorf("expected the placeholder: %s", out)
+		}
+	}
+
+	// the redacted error still unwraps to the error of the api server
+	var err = rd.redactError(apiErr)
+	if strings.Contains(err.Error(), decoded) || !apierrors.IsInvalid(err) || !errors.Is(err, apiErr) {
+		t.Errorf("unexpected redacted error %v", err)
+	}
+
+	// without the annotation, the verbosity of the manager applies
+	logged = nil
+	objectLogger(base, nil, nil).V(1).Info("not logged")
+	if len(logged) != 0 {
+		t.Errorf("expected no logs, got %v", logged)
+	}
+}
+
+func TestRedactor(t *testing.T) {
+	var rd = &redactor{}
+	rd.add(map[string]string{
+		"enabled": "yes",
+		"token":   "abcdef",
+		"suffix":  "defghi",
+		"long":    "abcdef-long",
+		"name":    "token",
+	})
+
+	for _, tt := range []struct {
+		name, in, out string
+	}{
+		{"short values", "yes, the pod is ready", globalsv1beta2.Redacted + ", the pod is ready"},
+		{"values, which are keys", "the key token is invalid", "the key token is invalid"},
+		{"value", "token abcdef is invalid", "token " + globalsv1beta2.Redacted + " is invalid"},
+		{"longest value", "token abcdef-long is invalid", "token " + globalsv1beta2.Redacted + " is invalid"},
+		{"overlapping values", "xxabcdefghixx", "xx" + globalsv1beta2.Redacted + "xx"},
+		{"repeated values", "abcdef abcdef", globalsv1beta2.Redacted + " " + globalsv1beta2.Redacted},
+		{"no value", "nothing to redact", "nothing to redact"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rd.redact(tt.in); got != tt.out {
+				t.Errorf("expected %q, got %q", tt.out, got)
+			}
+		})
+	}
+
+	// the key value pairs are redacted by their key and by their whole value
+	var got = rd.redactValues([]interface{}{"enabled", "no", "data", map[string]string{"a": "b"}, "value", "yes", "phase", "yes-no"})
+	var want = []interface{}{"enabled", globalsv1beta2.Redacted, "data", globalsv1beta2.Redacted, "value", globalsv1beta2.Redacted, "phase", globalsv1beta2.Redacted + "-no"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected %v at %d, got %v", want[i], i, got[i])
+		}
+	}
+
+	// the zero value does not redact anything
+	if got := (&redactor{}).redact("abcdef"); got != "abcdef" {
+		t.Errorf("expected no redaction, got %q", got)
+	}
+}
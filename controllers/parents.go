@@ -0,0 +1,86 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	globalsv1beta2 "github.com/jnnkrdb/configrdb/api/v1beta2"
+)
+
+// the kinds of the global objects, which are set as label on the replicated objects
+const (
+	kindGlobalConfig string = "GlobalConfig"
+	kindGlobalSecret string = "GlobalSecret"
+)
+
+// enqueue the global object of the given kind, whenever one of its replicated
+// objects changes, the global object is read from the labels and annotations of
+// the replicated object, so no global objects have to be listed
+func enqueueParent(kind string) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(obj client.Object) []reconcile.Request {
+		if parentKind, key, ok := globalsv1beta2.ParentOf(obj); ok && parentKind == kind {
+			return []reconcile.Request{{NamespacedName: key}}
+		}
+		return nil
+	})
+}
+
+// add the labels and annotations, which point to the global object, and the keyed
+// content hash to a replicated object, which was created by a former version of the
+// operator, the data of the object is not changed
+func patchParentMetadata(ctx context.Context, c client.Client, obj client.Object, kind string, parent client.Object, hash string) error {
+
+	var patch = client.MergeFrom(obj.DeepCopyObject().(client.Object))
+
+	var labels = obj.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	for k, v := range globalsv1beta2.Labels(kind, parent) {
+		labels[k] = v
+	}
+	obj.SetLabels(labels)
+
+	var annotations = obj.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[globalsv1beta2.AnnotationParentName] = parent.GetName()
+	annotations[globalsv1beta2.AnnotationContentHash] = hash
+	obj.SetAnnotations(annotations)
+
+	return c.Patch(ctx, obj, patch)
+}
+
+// check, whether a replicated object carries the data of the global object, the
+// objects of former versions carry the unkeyed content hash, they are annotated with
+// the keyed hash by patchParentMetadata instead of being replaced
+func hasContent(obj client.Object, hash, unkeyed string) bool {
+	var current = obj.GetAnnotations()[globalsv1beta2.AnnotationContentHash]
+	return current == hash || current == unkeyed
+}
+
+// check, whether a replicated object needs the metadata of patchParentMetadata
+func needsParentMetadata(obj client.Object, hash string) bool {
+	_, _, ok := globalsv1beta2.ParentOf(obj)
+	return !ok || obj.GetAnnotations()[globalsv1beta2.AnnotationContentHash] != hash
+}
@@ -0,0 +1,95 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"sort"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	globalsv1beta2 "github.com/jnnkrdb/configrdb/api/v1beta2"
+)
+
+// calculate the changes, which replicate a global object into the matching namespaces
+//
+// owned contains the namespaces of the replicated objects, which carry the uid of the
+// global object, and whether they contain the current data, only these objects are
+// updated or removed, a matching namespace without an owned object is checked for an
+// object with the same name, e.g. a configmap of another tool, which is reported as
+// conflict and never touched
+//
+// the plan is reported in dry run mode and applied by the reconciliation otherwise,
+// so both always agree on the changes
+func planChanges(ctx context.Context, c client.Reader, newObj func() client.Object, name, hash string, matches, avoids []v1.Namespace, owned map[string]bool) (*globalsv1beta2.ReplicationPlan, error) {
+
+	var plan = &globalsv1beta2.ReplicationPlan{
+		ContentHash: hash,
+		Matched:     int32(len(matches)),
+		Avoided:     int32(len(avoids)),
+	}
+
+	for i := range avoids {
+		if _, ok := owned[avoids[i].Name]; ok {
+			plan.Delete = append(plan.Delete, avoids[i].Name)
+		}
+	}
+
+	for i := range matches {
+		if inSync, ok := owned[matches[i].Name]; ok {
+			if !inSync {
+				plan.Update = append(plan.Update, matches[i].Name)
+			}
+			continue
+		}
+
+		err := c.Get(ctx, types.NamespacedName{Namespace: matches[i].Name, Name: name}, newObj())
+		switch {
+		case errors.IsNotFound(err):
+			plan.Create = append(plan.Create, matches[i].Name)
+		case err != nil:
+			return nil, err
+		default:
+			plan.Conflicts = append(plan.Conflicts, matches[i].Name)
+		}
+	}
+
+	sort.Strings(plan.Create)
+	sort.Strings(plan.Update)
+	sort.Strings(plan.Delete)
+	sort.Strings(plan.Conflicts)
+	return plan, nil
+}
+
+// the number of replicated objects, which drifted from the global object
+func driftOf(plan *globalsv1beta2.ReplicationPlan) int {
+	return len(plan.Create) + len(plan.Update) + len(plan.Delete) + len(plan.Conflicts)
+}
+
+// the namespaces of a list as set
+func namespaceSet(namespaces ...[]string) map[string]bool {
+	var set = make(map[string]bool)
+	for _, list := range namespaces {
+		for _, ns := range list {
+			set[ns] = true
+		}
+	}
+	return set
+}
@@ -0,0 +1,297 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	globalsv1beta2 "github.com/jnnkrdb/configrdb/api/v1beta2"
+)
+
+// the key of the content hashes of the tests
+var testHashKey = []byte("0123456789abcdef0123456789abcdef")
+
+// a client, which records the namespaces of the created and removed replicated objects
+type writeRecorder struct {
+	client.Client
+	name             string
+	created, deleted []string
+}
+
+func (c *writeRecorder) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if obj.GetName() == c.name && isReplica(obj) {
+		c.created = append(c.created, obj.GetNamespace())
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *writeRecorder) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if obj.GetName() == c.name && isReplica(obj) {
+		c.deleted = append(c.deleted, obj.GetNamespace())
+	}
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+// the executed changes as plan, an update deletes and recreates the object
+func (c *writeRecorder) plan() *globalsv1beta2.ReplicationPlan {
+	var plan = &globalsv1beta2.ReplicationPlan{}
+	var created = namespaceSet(c.created)
+	for _, ns := range c.deleted {
+		if created[ns] {
+			plan.Update = append(plan.Update, ns)
+			delete(created, ns)
+		} else {
+			plan.Delete = append(plan.Delete, ns)
+		}
+	}
+	for ns := range created {
+		plan.Create = append(plan.Create, ns)
+	}
+	sort.Strings(plan.Create)
+	sort.Strings(plan.Update)
+	sort.Strings(plan.Delete)
+	return plan
+}
+
+// the replicated configmaps and secrets, the revisions of the global objects are no replicas
+func isReplica(obj client.Object) bool {
+	switch o := obj.(type) {
+	case *v1.ConfigMap:
+		return true
+	case *v1.Secret:
+		return o.Type != revisionSecretType
+	}
+	return false
+}
+
+func newPlanScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	var scheme = runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := globalsv1beta2.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	return scheme
+}
+
+// the namespaces of the tests, team-* are matched, kube-* are avoided
+func planNamespaces() []client.Object {
+	var objs []client.Object
+	for _, name := range []string{"team-a", "team-b", "team-c", "team-d", "kube-x", "kube-y"} {
+		var ns = &v1.Namespace{}
+		ns.Name = name
+		objs = append(objs, ns)
+	}
+	return objs
+}
+
+// compare the changes, which were executed, with the plan of the dry run
+func comparePlans(t *testing.T, planned, executed *globalsv1beta2.ReplicationPlan) {
+	t.Helper()
+	for _, c := range []struct {
+		action            string
+		planned, executed []string
+	}{
+		{"create", planned.Create, executed.Create},
+		{"update", planned.Update, executed.Update},
+		{"delete", planned.Delete, executed.Delete},
+	} {
+		if fmt.Sprint(c.planned) != fmt.Sprint(c.executed) {
+			t.Errorf("planned to %s %q, executed %q", c.action, c.planned, c.executed)
+		}
+	}
+}
+
+func TestGlobalConfigPlanIsExecuted(t *testing.T) {
+	var ctx = context.Background()
+	var scheme = newPlanScheme(t)
+
+	var gc = &globalsv1beta2.GlobalConfig{}
+	gc.Name, gc.Namespace, gc.UID = "gc", "default", "uid-gc"
+	gc.Spec.Namespaces = globalsv1beta2.NamespacesRegex{MatchRegex: []string{"^team-"}}
+	gc.Spec.Data = map[string]string{"key": "value"}
+	gc.Spec.DryRun = true
+	var hash = globalsv1beta2.ContentHash(testHashKey, gc.Spec.Data)
+
+	// team-a is in sync, team-b is outdated, team-c is missing, team-d and kube-y contain a
+	// configmap with the same name, which is not owned, kube-x contains an owned copy
+	var replica = func(namespace, hash string, owned bool) client.Object {
+		var cm = &v1.ConfigMap{}
+		cm.Name, cm.Namespace = gc.Name, namespace
+		cm.Data = map[string]string{"key": "other"}
+		if owned {
+			cm.Labels = globalsv1beta2.Labels(kindGlobalConfig, gc)
+			cm.Annotations = globalsv1beta2.Annotations(hash, gc)
+		}
+		return cm
+	}
+	var c = &writeRecorder{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(append(planNamespaces(), gc,
+			replica("team-a", hash, true), replica("team-b", "outdated", true), replica("team-d", hash, false),
+			replica("kube-x", hash, true), replica("kube-y", hash, false))...).Build(),
+		name: gc.Name,
+	}
+	var r = &GlobalConfigReconciler{Client: c, Scheme: scheme, HashKey: testHashKey}
+	var req = ctrl.Request{NamespacedName: types.NamespacedName{Namespace: gc.Namespace, Name: gc.Name}}
+
+	// the dry run only reports the plan
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.created)+len(c.deleted) > 0 {
+		t.Fatalf("the dry run touched the configmaps, created %q, deleted %q", c.created, c.deleted)
+	}
+	if err := c.Get(ctx, req.NamespacedName, gc); err != nil {
+		t.Fatal(err)
+	}
+	var planned = gc.Status.Plan
+	if planned == nil {
+		t.Fatal("expected a plan")
+	}
+	if fmt.Sprint(planned.Create, planned.Update, planned.Delete, planned.Conflicts) != "[team-c] [team-b] [kube-x] [team-d]" {
+		t.Errorf("unexpected plan %+v", planned)
+	}
+
+	// the reconciliation executes exactly the plan
+	gc.Spec.DryRun = false
+	if err := c.Update(ctx, gc); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	comparePlans(t, planned, c.plan())
+
+	// the configmaps, which are not owned, are untouched
+	for _, ns := range []string{"team-d", "kube-y"} {
+		var cm = &v1.ConfigMap{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: ns, Name: gc.Name}, cm); err != nil || cm.Data["key"] != "other" {
+			t.Errorf("the configmap in %s was touched: %v %v", ns, cm.Data, err)
+		}
+	}
+}
+
+func TestGlobalSecretPlanIsExecuted(t *testing.T) {
+	var ctx = context.Background()
+	var scheme = newPlanScheme(t)
+
+	var gs = &globalsv1beta2.GlobalSecret{}
+	gs.Name, gs.Namespace, gs.UID = "gs", "default", "uid-gs"
+	gs.Spec.Namespaces = globalsv1beta2.NamespacesRegex{MatchRegex: []string{"^team-"}}
+	gs.Spec.Data = globalsv1beta2.SecretData{"key": "dmFsdWU="}
+	gs.Spec.Type = string(v1.SecretTypeOpaque)
+	gs.Spec.DryRun = true
+	var hash = globalsv1beta2.ContentHash(testHashKey, gs.Spec.Data)
+
+	// team-a is in sync, team-b changed its type, team-c is missing, team-d contains a
+	// secret with the same name, which is not owned, kube-x contains an owned copy
+	var replica = func(namespace string, secretType v1.SecretType, owned bool) client.Object {
+		var scrt = &v1.Secret{}
+		scrt.Name, scrt.Namespace, scrt.Type = gs.Name, namespace, secretType
+		scrt.Data = map[string][]byte{"key": []byte("other")}
+		if owned {
+			scrt.Labels = globalsv1beta2.Labels(kindGlobalSecret, gs)
+			scrt.Annotations = globalsv1beta2.Annotations(hash, gs)
+		}
+		return scrt
+	}
+	var c = &writeRecorder{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(append(planNamespaces(), gs,
+			replica("team-a", v1.SecretTypeOpaque, true), replica("team-b", v1.SecretTypeTLS, true),
+			replica("team-d", v1.SecretTypeOpaque, false), replica("kube-x", v1.SecretTypeOpaque, true))...).Build(),
+		name: gs.Name,
+	}
+	var r = &GlobalSecretReconciler{Client: c, Scheme: scheme, HashKey: testHashKey}
+	var req = ctrl.Request{NamespacedName: types.NamespacedName{Namespace: gs.Namespace, Name: gs.Name}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(ctx, req.NamespacedName, gs); err != nil {
+		t.Fatal(err)
+	}
+	var planned = gs.Status.Plan
+	if planned == nil {
+		t.Fatal("expected a plan")
+	}
+	if fmt.Sprint(planned.Create, planned.Update, planned.Delete, planned.Conflicts) != "[team-c] [team-b] [kube-x] [team-d]" {
+		t.Errorf("unexpected plan %+v", planned)
+	}
+
+	gs.Spec.DryRun = false
+	if err := c.Update(ctx, gs); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	comparePlans(t, planned, c.plan())
+
+	var scrt = &v1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "team-d", Name: gs.Name}, scrt); err != nil || string(scrt.Data["key"]) != "other" {
+		t.Errorf("the secret in team-d was touched: %v %v", scrt.Data, err)
+	}
+}
+
+func TestUnkeyedContentHashIsPatched(t *testing.T) {
+	var ctx = context.Background()
+	var scheme = newPlanScheme(t)
+
+	var gc = &globalsv1beta2.GlobalConfig{}
+	gc.Name, gc.Namespace, gc.UID = "gc", "default", "uid-gc"
+	gc.Spec.Namespaces = globalsv1beta2.NamespacesRegex{MatchRegex: []string{"^team-a$"}}
+	gc.Spec.Data = map[string]string{"key": "value"}
+
+	// a former version annotated the configmap with the unkeyed content hash
+	var cm = &v1.ConfigMap{}
+	cm.Name, cm.Namespace = gc.Name, "team-a"
+	cm.Data = gc.Spec.Data
+	cm.Labels = globalsv1beta2.Labels(kindGlobalConfig, gc)
+	cm.Annotations = globalsv1beta2.Annotations(globalsv1beta2.UnkeyedContentHash(gc.Spec.Data), gc)
+
+	var c = &writeRecorder{
+		Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(append(planNamespaces(), gc, cm)...).Build(),
+		name:   gc.Name,
+	}
+	var r = &GlobalConfigReconciler{Client: c, Scheme: scheme, HashKey: testHashKey}
+	var req = ctrl.Request{NamespacedName: types.NamespacedName{Namespace: gc.Namespace, Name: gc.Name}}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.created)+len(c.deleted) > 0 {
+		t.Fatalf("the configmap was replaced, created %q, deleted %q", c.created, c.deleted)
+	}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: "team-a", Name: gc.Name}, cm); err != nil {
+		t.Fatal(err)
+	}
+	if cm.Annotations[globalsv1beta2.AnnotationContentHash] != globalsv1beta2.ContentHash(testHashKey, gc.Spec.Data) {
+		t.Errorf("expected the keyed content hash, got %q", cm.Annotations[globalsv1beta2.AnnotationContentHash])
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	globalsv1beta2 "github.com/jnnkrdb/configrdb/api/v1beta2"
+)
+
+//+kubebuilder:rbac:groups=globals.jnnkrdb.de,resources=globalreplicationpolicies,verbs=get;list;watch
+
+// get the violations of all globalreplicationpolicies by a global object, which
+// targets the matching namespaces
+//
+// secretType is empty for GlobalConfigs, the caller does not touch the replicated
+// objects of a violating global object, so the existing copies stay in place
+func policyViolations(ctx context.Context, c client.Client, obj client.Object, secretType string, matches []v1.Namespace) ([]string, error) {
+
+	var policyList = &globalsv1beta2.GlobalReplicationPolicyList{}
+	if err := c.List(ctx, policyList); err != nil {
+		return nil, err
+	}
+
+	var targets = make([]string, 0, len(matches))
+	for i := range matches {
+		targets = append(targets, matches[i].Name)
+	}
+
+	var violations []string
+	for i := range policyList.Items {
+		violations = append(violations, policyList.Items[i].Violations(obj, secretType, targets)...)
+	}
+	return violations, nil
+}
+
+// enqueue all global objects of the given list type, whenever a
+// globalreplicationpolicy changes, since a policy applies to all of them
+func enqueueAll(c client.Client, newList func() client.ObjectList) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(client.Object) []reconcile.Request {
+
+		var list = newList()
+		if err := c.List(context.Background(), list); err != nil {
+			return nil
+		}
+
+		var items, err = meta.ExtractList(list)
+		if err != nil {
+			return nil
+		}
+
+		var requests = make([]reconcile.Request, 0, len(items))
+		for _, item := range items {
+			if obj, ok := item.(client.Object); ok {
+				requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{
+					Namespace: obj.GetNamespace(),
+					Name:      obj.GetName(),
+				}})
+			}
+		}
+		return requests
+	})
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"sync"
+
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	globalsv1beta2 "github.com/jnnkrdb/configrdb/api/v1beta2"
+)
+
+// cache of the compiled namespace regexpressions of the global objects
+//
+// the regexpressions are compiled once per generation of a global object, since
+// the spec can not change without a new generation
+type namespacesRegexCache struct {
+	mu      sync.Mutex
+	entries map[types.NamespacedName]namespacesRegexCacheEntry
+}
+
+type namespacesRegexCacheEntry struct {
+	uid        types.UID
+	generation int64
+	compiled   *globalsv1beta2.CompiledNamespacesRegex
+}
+
+// get the compiled regexpressions of a global object, the regexpressions are
+// compiled, if the generation of the object is not cached yet
+func (c *namespacesRegexCache) get(obj client.Object, nsr globalsv1beta2.NamespacesRegex) (*globalsv1beta2.CompiledNamespacesRegex, error) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var key = client.ObjectKeyFromObject(obj)
+	if entry, ok := c.entries[key]; ok && entry.uid == obj.GetUID() && entry.generation == obj.GetGeneration() {
+		return entry.compiled, nil
+	}
+
+	compiled, err := nsr.Compile()
+	if err != nil {
+		return nil, err
+	}
+
+	if c.entries == nil {
+		c.entries = make(map[types.NamespacedName]namespacesRegexCacheEntry)
+	}
+	c.entries[key] = namespacesRegexCacheEntry{
+		uid:        obj.GetUID(),
+		generation: obj.GetGeneration(),
+		compiled:   compiled,
+	}
+	return compiled, nil
+}
+
+// remove the compiled regexpressions of a deleted global object
+func (c *namespacesRegexCache) forget(key types.NamespacedName) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+}
@@ -0,0 +1,36 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// get the time, after which a global object is reconciled again without an event,
+// the interval of the global object overrides the interval of the operator and
+// 0 disables the periodic reconciliation
+func resyncAfter(period time.Duration, interval *metav1.Duration) time.Duration {
+	if interval != nil {
+		period = interval.Duration
+	}
+	if period < 0 {
+		return 0
+	}
+	return period
+}
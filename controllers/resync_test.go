@@ -0,0 +1,49 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestResyncAfter(t *testing.T) {
+	var interval = func(d time.Duration) *metav1.Duration { return &metav1.Duration{Duration: d} }
+
+	for _, tt := range []struct {
+		name     string
+		period   time.Duration
+		interval *metav1.Duration
+		want     time.Duration
+	}{
+		{"period of the operator", 10 * time.Hour, nil, 10 * time.Hour},
+		{"disabled by the operator", 0, nil, 0},
+		{"negative period", -time.Minute, nil, 0},
+		{"interval overrides the period", 10 * time.Hour, interval(5 * time.Minute), 5 * time.Minute},
+		{"interval without a period", 0, interval(time.Minute), time.Minute},
+		{"interval disables the resync", 10 * time.Hour, interval(0), 0},
+		{"negative interval", 10 * time.Hour, interval(-time.Minute), 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resyncAfter(tt.period, tt.interval); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	globalsv1beta2 "github.com/jnnkrdb/configrdb/api/v1beta2"
+)
+
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;patch;delete
+
+// the revisions of the global objects are stored in secrets of this type, in the
+// namespace of the global object
+//
+// secrets are used for both kinds, since the data of a globalsecret must not become
+// readable for everyone, who is allowed to read controllerrevisions or configmaps
+const revisionSecretType v1.SecretType = "globals.jnnkrdb.de/revision"
+
+// the requested revision does not exist
+var errRevisionNotFound = errors.New("revision not found")
+
+// get the labels of the revisions of a global object
+func revisionLabels(owner client.Object) client.MatchingLabels {
+	return client.MatchingLabels{
+		"globals.jnnkrdb.de/revision.uid": string(owner.GetUID()),
+	}
+}
+
+// get the number of a revision
+func revisionNumber(revision *v1.Secret) int64 {
+	n, _ := strconv.ParseInt(revision.Annotations[globalsv1beta2.AnnotationRevision], 10, 64)
+	return n
+}
+
+// list all the revisions of a global object, the newest revision comes first
+func listRevisions(ctx context.Context, c client.Client, owner client.Object) ([]v1.Secret, error) {
+
+	var secretList = &v1.SecretList{}
+	if err := c.List(ctx, secretList, client.InNamespace(owner.GetNamespace()), revisionLabels(owner)); err != nil {
+		return nil, err
+	}
+
+	var revisions = make([]v1.Secret, 0, len(secretList.Items))
+	for i := range secretList.Items {
+		if secretList.Items[i].Type == revisionSecretType {
+			revisions = append(revisions, secretList.Items[i])
+		}
+	}
+	sort.Slice(revisions, func(i, j int) bool {
+		return revisionNumber(&revisions[i]) > revisionNumber(&revisions[j])
+	})
+	return revisions, nil
+}
+
+// record the data of a global object as its newest revision and remove the
+// revisions, which exceed the limit
+//
+// if the data equals a former revision, e.g. after a rollback, the former
+// revision is reused and becomes the newest revision
+func recordRevision(ctx context.Context, c client.Client, scheme *runtime.Scheme, owner client.Object, data map[string]string, secretType string, limit *int32) (int64, error) {
+
+	var revisions, err = listRevisions(ctx, c, owner)
+	if err != nil {
+		return 0, err
+	}
+
+	var keep = globalsv1beta2.DefaultRevisionHistoryLimit
+	if limit != nil {
+		keep = *limit
+	}
+
+	// the revisions contain the data, so their hash is not keyed
+	var hash = globalsv1beta2.UnkeyedContentHash(data)
+	var current int64
+	if keep > 0 {
+		var latest int64
+		var match *v1.Secret
+		for i := range revisions {
+			if n := revisionNumber(&revisions[i]); n > latest {
+				latest = n
+			}
+			if revisions[i].Annotations[globalsv1beta2.AnnotationContentHash] == hash && string(revisions[i].Data["type"]) == secretType {
+				match = &revisions[i]
+			}
+		}
+
+		switch {
+		case match != nil && revisionNumber(match) == latest:
+			current = latest
+
+		case match != nil:
+			// the data was rolled back, so the former revision becomes the newest one
+			current = latest + 1
+			patch := client.MergeFrom(match.DeepCopy())
+			match.Annotations[globalsv1beta2.AnnotationRevision] = strconv.FormatInt(current, 10)
+			if err = c.Patch(ctx, match, patch); err != nil {
+				return 0, err
+			}
+
+		default:
+			current = latest + 1
+			var encoded []byte
+			if encoded, err = json.Marshal(data); err != nil {
+				return 0, err
+			}
+
+			var revision = &v1.Secret{}
+			revision.Name = revisionName(owner.GetName(), globalsv1beta2.UnkeyedContentHash(map[string]string{"data": hash, "type": secretType}))
+			revision.Namespace = owner.GetNamespace()
+			revision.Type = revisionSecretType
+			revision.Labels = revisionLabels(owner)
+			revision.Annotations = map[string]string{
+				globalsv1beta2.AnnotationRevision:    strconv.FormatInt(current, 10),
+				globalsv1beta2.AnnotationContentHash: hash,
+			}
+			revision.Data = map[string][]byte{"data": encoded, "type": []byte(secretType)}
+			if err = controllerutil.SetControllerReference(owner, revision, scheme); err != nil {
+				return 0, err
+			}
+			if err = c.Create(ctx, revision, &client.CreateOptions{}); err != nil {
+				return 0, err
+			}
+			revisions = append([]v1.Secret{*revision}, revisions...)
+		}
+
+		sort.Slice(revisions, func(i, j int) bool {
+			return revisionNumber(&revisions[i]) > revisionNumber(&revisions[j])
+		})
+	}
+
+	// remove the oldest revisions
+	for i := int(keep); i < len(revisions); i++ {
+		if err = c.Delete(ctx, &revisions[i], &client.DeleteOptions{}); client.IgnoreNotFound(err) != nil {
+			return 0, err
+		}
+	}
+	return current, nil
+}
+
+// get the data of a former revision of a global object
+func getRevision(ctx context.Context, c client.Client, owner client.Object, number string) (data map[string]string, secretType string, err error) {
+
+	var n int64
+	if n, err = strconv.ParseInt(number, 10, 64); err != nil {
+		return nil, "", fmt.Errorf("%w: invalid revision number %q", errRevisionNotFound, number)
+	}
+
+	var revisions []v1.Secret
+	if revisions, err = listRevisions(ctx, c, owner); err != nil {
+		return nil, "", err
+	}
+
+	for i := range revisions {
+		if revisionNumber(&revisions[i]) != n {
+			continue
+		}
+		if err = json.Unmarshal(revisions[i].Data["data"], &data); err != nil {
+			return nil, "", err
+		}
+		return data, string(revisions[i].Data["type"]), nil
+	}
+	return nil, "", fmt.Errorf("%w: revision %d", errRevisionNotFound, n)
+}
+
+// check, whether the error tells, that the requested revision does not exist
+func isRevisionNotFound(err error) bool {
+	return errors.Is(err, errRevisionNotFound)
+}
+
+// get the name of a revision, which consists of the name of the global object
+// and the beginning of the content hash
+func revisionName(name, hash string) string {
+	if len(name) > 240 {
+		name = name[:240]
+	}
+	return fmt.Sprintf("%s-%s", name, hash[:10])
+}
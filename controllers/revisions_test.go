@@ -0,0 +1,170 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	globalsv1beta2 "github.com/jnnkrdb/configrdb/api/v1beta2"
+)
+
+func TestRecordRevision(t *testing.T) {
+	var ctx = context.Background()
+	var scheme = newPlanScheme(t)
+
+	var gs = &globalsv1beta2.GlobalSecret{}
+	gs.Name, gs.Namespace, gs.UID = "gs", "default", "uid-gs"
+	var c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(gs).Build()
+
+	var limit int32 = 2
+	var record = func(data map[string]string, secretType string, want int64) {
+		t.Helper()
+		if n, err := recordRevision(ctx, c, scheme, gs, data, secretType, &limit); err != nil || n != want {
+			t.Fatalf("expected the revision %d, got %d (%v)", want, n, err)
+		}
+	}
+	var numbers = func() (numbers []int64) {
+		t.Helper()
+		revisions, err := listRevisions(ctx, c, gs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range revisions {
+			numbers = append(numbers, revisionNumber(&revisions[i]))
+		}
+		return numbers
+	}
+
+	var a, b, d = map[string]string{"key": "YQ=="}, map[string]string{"key": "Yg=="}, map[string]string{"key": "ZA=="}
+	record(a, "Opaque", 1)
+	record(a, "Opaque", 1)
+	record(b, "Opaque", 2)
+	record(d, "Opaque", 3)
+
+	// the oldest revision exceeds the history limit
+	if got := numbers(); len(got) != 2 || got[0] != 3 || got[1] != 2 {
+		t.Errorf("expected the revisions [3 2], got %v", got)
+	}
+	if _, _, err := getRevision(ctx, c, gs, "1"); !isRevisionNotFound(err) {
+		t.Errorf("expected the trimmed revision to be missing, got %v", err)
+	}
+
+	// the data of a former revision is reused as newest revision
+	record(b, "Opaque", 4)
+	if got := numbers(); len(got) != 2 || got[0] != 4 || got[1] != 3 {
+		t.Errorf("expected the revisions [4 3], got %v", got)
+	}
+
+	// the same data with another type is a new revision
+	record(b, "kubernetes.io/tls", 5)
+	data, secretType, err := getRevision(ctx, c, gs, "5")
+	if err != nil || data["key"] != b["key"] || secretType != "kubernetes.io/tls" {
+		t.Errorf("unexpected revision %v %s (%v)", data, secretType, err)
+	}
+	if data, secretType, err = getRevision(ctx, c, gs, "4"); err != nil || data["key"] != b["key"] || secretType != "Opaque" {
+		t.Errorf("unexpected revision %v %s (%v)", data, secretType, err)
+	}
+
+	// without a history, all revisions are removed
+	limit = 0
+	record(a, "Opaque", 0)
+	if got := numbers(); len(got) != 0 {
+		t.Errorf("expected no revisions, got %v", got)
+	}
+}
+
+func TestGetRevision(t *testing.T) {
+	var ctx = context.Background()
+	var scheme = newPlanScheme(t)
+
+	var gc = &globalsv1beta2.GlobalConfig{}
+	gc.Name, gc.Namespace, gc.UID = "gc", "default", "uid-gc"
+	var c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(gc).Build()
+	if _, err := recordRevision(ctx, c, scheme, gc, map[string]string{"key": "value"}, "", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, number := range []string{"2", "0", "latest", ""} {
+		if _, _, err := getRevision(ctx, c, gc, number); !isRevisionNotFound(err) {
+			t.Errorf("%q: expected a missing revision, got %v", number, err)
+		}
+	}
+	if data, _, err := getRevision(ctx, c, gc, "1"); err != nil || data["key"] != "value" {
+		t.Errorf("unexpected revision %v (%v)", data, err)
+	}
+}
+
+func TestGlobalSecretRollback(t *testing.T) {
+	var ctx = context.Background()
+	var scheme = newPlanScheme(t)
+
+	var gs = &globalsv1beta2.GlobalSecret{}
+	gs.Name, gs.Namespace, gs.UID = "gs", "default", "uid-gs"
+	gs.Finalizers = []string{globalsv1beta2.FinalizerGlobal}
+	var c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(gs).Build()
+	var r = &GlobalSecretReconciler{Client: c, Scheme: scheme}
+	var req = ctrl.Request{NamespacedName: types.NamespacedName{Namespace: gs.Namespace, Name: gs.Name}}
+
+	// the first revision was an opaque secret, the current one is a tls secret
+	var opaque = map[string]string{"key": "dmFsdWU="}
+	var tls = map[string]string{v1.TLSCertKey: "Y2VydA==", v1.TLSPrivateKeyKey: "a2V5"}
+	if _, err := recordRevision(ctx, c, scheme, gs, opaque, string(v1.SecretTypeOpaque), nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := recordRevision(ctx, c, scheme, gs, tls, string(v1.SecretTypeTLS), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var rollback = func(revision string) {
+		t.Helper()
+		if err := c.Get(ctx, req.NamespacedName, gs); err != nil {
+			t.Fatal(err)
+		}
+		gs.Spec.Data, gs.Spec.Type = tls, string(v1.SecretTypeTLS)
+		gs.Annotations = map[string]string{globalsv1beta2.AnnotationRollbackTo: revision}
+		if err := c.Update(ctx, gs); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.Reconcile(ctx, req); err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Get(ctx, req.NamespacedName, gs); err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := gs.Annotations[globalsv1beta2.AnnotationRollbackTo]; ok {
+			t.Errorf("%s: expected the rollback request to be removed", revision)
+		}
+	}
+
+	// a missing revision drops the request and keeps the data
+	rollback("7")
+	if gs.Spec.Type != string(v1.SecretTypeTLS) || len(gs.Spec.Data) != 2 {
+		t.Errorf("expected the data to be kept, got %s %v", gs.Spec.Type, gs.Spec.Data)
+	}
+
+	// the rollback restores the data and the type of the revision
+	rollback("1")
+	if gs.Spec.Type != string(v1.SecretTypeOpaque) || len(gs.Spec.Data) != 1 || gs.Spec.Data["key"] != opaque["key"] {
+		t.Errorf("expected the opaque revision, got %s %v", gs.Spec.Type, gs.Spec.Data)
+	}
+}
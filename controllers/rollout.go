@@ -0,0 +1,187 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/jnnkrdb/configrdb/internal/audit"
+)
+
+//+kubebuilder:rbac:groups=apps,resources=deployments;statefulsets;daemonsets,verbs=get;list;watch;patch
+
+// the kinds of the replicated objects, which can be consumed by workloads
+const (
+	kindConfigMap string = "ConfigMap"
+	kindSecret    string = "Secret"
+)
+
+// get the annotation key, which is set on the pod templates of the workloads
+// consuming the replicated object
+//
+// the name part of an annotation key can not be longer than 63 characters, so
+// long object names are replaced by a hash of the name
+func rolloutAnnotation(kind, name string) string {
+	var key = fmt.Sprintf("%s.%s", strings.ToLower(kind), name)
+	if len(key) > 63 {
+		sum := sha256.Sum256([]byte(name))
+		key = fmt.Sprintf("%s.%s", strings.ToLower(kind), hex.EncodeToString(sum[:])[:16])
+	}
+	return "checksum.globals.jnnkrdb.de/" + key
+}
+
+// patch the pod templates of all Deployments, StatefulSets and DaemonSets in the
+// namespace, which consume the replicated object, with the given content hash
+//
+// changing the annotation of the pod template triggers a rolling restart of the
+// workload, workloads, which already carry the current hash, stay untouched, if the
+// replicated object was not changed, only the workloads with the hash of a former
+// content are restarted, e.g. after a failed restart, the workloads without the
+// annotation or with the unkeyed hash of a former version of the operator are not
+// restarted, since their pods already use the current content
+func rolloutWorkloads(ctx context.Context, c client.Client, l logr.Logger, a *audit.Auditor, parent audit.Object, namespace, kind, name, hash, unkeyed string, changed bool) error {
+
+	var deployments = &appsv1.DeploymentList{}
+	if err := c.List(ctx, deployments, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+
+	var statefulSets = &appsv1.StatefulSetList{}
+	if err := c.List(ctx, statefulSets, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+
+	var daemonSets = &appsv1.DaemonSetList{}
+	if err := c.List(ctx, daemonSets, client.InNamespace(namespace)); err != nil {
+		return err
+	}
+
+	// collect all the workloads with their pod templates
+	type workload struct {
+		kind     string
+		obj      client.Object
+		template *v1.PodTemplateSpec
+	}
+	var workloads []workload
+	for i := range deployments.Items {
+		workloads = append(workloads, workload{"Deployment", &deployments.Items[i], &deployments.Items[i].Spec.Template})
+	}
+	for i := range statefulSets.Items {
+		workloads = append(workloads, workload{"StatefulSet", &statefulSets.Items[i], &statefulSets.Items[i].Spec.Template})
+	}
+	for i := range daemonSets.Items {
+		workloads = append(workloads, workload{"DaemonSet", &daemonSets.Items[i], &daemonSets.Items[i].Spec.Template})
+	}
+
+	var key = rolloutAnnotation(kind, name)
+	for _, w := range workloads {
+		obj, template := w.obj, w.template
+
+		current, annotated := template.Annotations[key]
+		switch {
+		case !podSpecReferences(&template.Spec, kind, name), current == hash:
+			continue
+		case !changed && (!annotated || current == unkeyed):
+			continue
+		}
+
+		l.Info("restarting workload", "Workload", fmt.Sprintf("%T[%s/%s]", obj, obj.GetNamespace(), obj.GetName()))
+
+		patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+		if template.Annotations == nil {
+			template.Annotations = make(map[string]string)
+		}
+		template.Annotations[key] = hash
+		var err = c.Patch(ctx, obj, patch)
+
+		var rec = audit.Record{
+			Action:      audit.ActionRestart,
+			Outcome:     audit.OutcomeSuccess,
+			Parent:      parent,
+			Target:      audit.ObjectOf(w.kind, obj),
+			ContentHash: hash,
+		}
+		if err != nil {
+			rec.Outcome = audit.OutcomeError
+		}
+		if aErr := a.Record(ctx, rec); aErr != nil {
+			l.Error(aErr, "error recording the restart")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check, whether the pod spec references the configmap or secret with the given name
+// via volumes, projected volumes, envFrom or env.valueFrom
+func podSpecReferences(spec *v1.PodSpec, kind, name string) bool {
+
+	for _, vol := range spec.Volumes {
+		switch {
+		case kind == kindConfigMap && vol.ConfigMap != nil && vol.ConfigMap.Name == name:
+			return true
+		case kind == kindSecret && vol.Secret != nil && vol.Secret.SecretName == name:
+			return true
+		case vol.Projected != nil:
+			for _, src := range vol.Projected.Sources {
+				if kind == kindConfigMap && src.ConfigMap != nil && src.ConfigMap.Name == name {
+					return true
+				}
+				if kind == kindSecret && src.Secret != nil && src.Secret.Name == name {
+					return true
+				}
+			}
+		}
+	}
+
+	var containers = append(append([]v1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, ctr := range containers {
+
+		for _, envFrom := range ctr.EnvFrom {
+			if kind == kindConfigMap && envFrom.ConfigMapRef != nil && envFrom.ConfigMapRef.Name == name {
+				return true
+			}
+			if kind == kindSecret && envFrom.SecretRef != nil && envFrom.SecretRef.Name == name {
+				return true
+			}
+		}
+
+		for _, env := range ctr.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if kind == kindConfigMap && env.ValueFrom.ConfigMapKeyRef != nil && env.ValueFrom.ConfigMapKeyRef.Name == name {
+				return true
+			}
+			if kind == kindSecret && env.ValueFrom.SecretKeyRef != nil && env.ValueFrom.SecretKeyRef.Name == name {
+				return true
+			}
+		}
+	}
+	return false
+}
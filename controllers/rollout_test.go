@@ -0,0 +1,199 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/jnnkrdb/configrdb/internal/audit"
+)
+
+func TestPodSpecReferences(t *testing.T) {
+	var container = func(ctr v1.Container) v1.PodSpec { return v1.PodSpec{Containers: []v1.Container{ctr}} }
+	var volume = func(src v1.VolumeSource) v1.PodSpec {
+		return v1.PodSpec{Volumes: []v1.Volume{{Name: "vol", VolumeSource: src}}}
+	}
+	var cmRef = v1.LocalObjectReference{Name: "gc"}
+
+	for _, tt := range []struct {
+		name string
+		kind string
+		spec v1.PodSpec
+		want bool
+	}{
+		{"configmap volume", kindConfigMap, volume(v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{LocalObjectReference: cmRef}}), true},
+		{"secret volume", kindSecret, volume(v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: "gc"}}), true},
+		{"secret volume of a configmap", kindConfigMap, volume(v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: "gc"}}), false},
+		{"projected configmap", kindConfigMap, volume(v1.VolumeSource{Projected: &v1.ProjectedVolumeSource{Sources: []v1.VolumeProjection{
+			{Secret: &v1.SecretProjection{LocalObjectReference: v1.LocalObjectReference{Name: "other"}}},
+			{ConfigMap: &v1.ConfigMapProjection{LocalObjectReference: cmRef}},
+		}}}), true},
+		{"projected secret", kindSecret, volume(v1.VolumeSource{Projected: &v1.ProjectedVolumeSource{Sources: []v1.VolumeProjection{
+			{Secret: &v1.SecretProjection{LocalObjectReference: cmRef}},
+		}}}), true},
+		{"envFrom configmap", kindConfigMap, container(v1.Container{EnvFrom: []v1.EnvFromSource{{ConfigMapRef: &v1.ConfigMapEnvSource{LocalObjectReference: cmRef}}}}), true},
+		{"envFrom secret", kindSecret, container(v1.Container{EnvFrom: []v1.EnvFromSource{{SecretRef: &v1.SecretEnvSource{LocalObjectReference: cmRef}}}}), true},
+		{"env configmap key", kindConfigMap, container(v1.Container{Env: []v1.EnvVar{
+			{Name: "PLAIN", Value: "gc"},
+			{Name: "KEY", ValueFrom: &v1.EnvVarSource{ConfigMapKeyRef: &v1.ConfigMapKeySelector{LocalObjectReference: cmRef, Key: "key"}}},
+		}}), true},
+		{"env secret key", kindSecret, container(v1.Container{Env: []v1.EnvVar{
+			{Name: "KEY", ValueFrom: &v1.EnvVarSource{SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: cmRef, Key: "key"}}},
+		}}), true},
+		{"env of another configmap", kindConfigMap, container(v1.Container{Env: []v1.EnvVar{
+			{Name: "KEY", ValueFrom: &v1.EnvVarSource{ConfigMapKeyRef: &v1.ConfigMapKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "other"}}}},
+		}}), false},
+		{"init container", kindSecret, v1.PodSpec{InitContainers: []v1.Container{
+			{EnvFrom: []v1.EnvFromSource{{SecretRef: &v1.SecretEnvSource{LocalObjectReference: cmRef}}}},
+		}}, true},
+		{"no reference", kindConfigMap, container(v1.Container{Name: "gc"}), false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := podSpecReferences(&tt.spec, tt.kind, "gc"); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRolloutAnnotation(t *testing.T) {
+	for _, name := range []string{"gc", strings.Repeat("a", 253)} {
+		var key = rolloutAnnotation(kindConfigMap, name)
+		if parts := strings.SplitN(key, "/", 2); len(parts) != 2 || len(parts[1]) > 63 {
+			t.Errorf("invalid annotation key %s", key)
+		}
+	}
+}
+
+func TestRolloutWorkloads(t *testing.T) {
+	const hash = "new-hash"
+	var scheme = runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	var key = rolloutAnnotation(kindSecret, "gs")
+
+	var template = func(spec v1.PodSpec, annotations map[string]string) v1.PodTemplateSpec {
+		spec.Containers = append(spec.Containers, v1.Container{Name: "app", Image: "app"})
+		return v1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Annotations: annotations}, Spec: spec}
+	}
+	var envFrom = v1.PodSpec{Containers: []v1.Container{{Name: "env", EnvFrom: []v1.EnvFromSource{{SecretRef: &v1.SecretEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "gs"}}}}}}}
+	var initContainer = v1.PodSpec{InitContainers: []v1.Container{{Name: "init", Env: []v1.EnvVar{{Name: "KEY", ValueFrom: &v1.EnvVarSource{
+		SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: v1.LocalObjectReference{Name: "gs"}, Key: "key"},
+	}}}}}}
+
+	var referencing, current, unreferenced = &appsv1.Deployment{}, &appsv1.Deployment{}, &appsv1.DaemonSet{}
+	var initialized = &appsv1.StatefulSet{}
+	referencing.Name, referencing.Spec.Template = "referencing", template(envFrom, map[string]string{key: "old-hash"})
+	current.Name, current.Spec.Template = "current", template(envFrom, map[string]string{key: hash})
+	initialized.Name, initialized.Spec.Template = "initialized", template(initContainer, nil)
+	unreferenced.Name, unreferenced.Spec.Template = "unreferenced", template(v1.PodSpec{}, nil)
+	var workloads = []client.Object{referencing, current, initialized, unreferenced}
+	for _, obj := range workloads {
+		obj.SetNamespace("team-a")
+	}
+	var c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(workloads...).Build()
+
+	var records = &bytes.Buffer{}
+	var auditKey = []byte("0123456789abcdef0123456789abcdef")
+	var a = audit.New("confrdb-0", auditKey, audit.NewWriterSink(records))
+	if err := rolloutWorkloads(context.Background(), c, logr.Discard(), a, audit.Object{Kind: kindGlobalSecret, Name: "gs"}, "team-a", kindSecret, "gs", hash, "unkeyed-hash", true); err != nil {
+		t.Fatal(err)
+	}
+
+	var annotations = func(obj client.Object) map[string]string {
+		if err := c.Get(context.Background(), types.NamespacedName{Namespace: "team-a", Name: obj.GetName()}, obj); err != nil {
+			t.Fatal(err)
+		}
+		switch o := obj.(type) {
+		case *appsv1.Deployment:
+			return o.Spec.Template.Annotations
+		case *appsv1.StatefulSet:
+			return o.Spec.Template.Annotations
+		case *appsv1.DaemonSet:
+			return o.Spec.Template.Annotations
+		}
+		return nil
+	}
+	for _, obj := range []client.Object{referencing, current, initialized} {
+		if got := annotations(obj)[key]; got != hash {
+			t.Errorf("%s: expected the hash %s, got %q", obj.GetName(), hash, got)
+		}
+	}
+	if got, ok := annotations(unreferenced)[key]; ok {
+		t.Errorf("the unreferenced workload was annotated with %q", got)
+	}
+
+	// only the outdated workloads are restarted and audited
+	if n, err := audit.Verify(records, auditKey); err != nil || n != 2 {
+		t.Errorf("expected 2 restarts, got %d (%v)", n, err)
+	}
+}
+
+func TestRolloutWorkloadsOfUnchangedObject(t *testing.T) {
+	const hash = "new-hash"
+	var scheme = runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	var key = rolloutAnnotation(kindConfigMap, "gc")
+
+	// the rollout was enabled or the operator was upgraded, the copy of the configmap is
+	// in sync, only the workload, which was restarted for a former content, is outdated
+	var workloads = map[string]map[string]string{
+		"unannotated": nil,
+		"unkeyed":     {key: "unkeyed-hash"},
+		"outdated":    {key: "old-hash"},
+	}
+	var objs []client.Object
+	for name, annotations := range workloads {
+		var deploy = &appsv1.Deployment{}
+		deploy.Name, deploy.Namespace = name, "team-a"
+		deploy.Spec.Template.Annotations = annotations
+		deploy.Spec.Template.Spec.Containers = []v1.Container{{Name: "app", Image: "app", EnvFrom: []v1.EnvFromSource{
+			{ConfigMapRef: &v1.ConfigMapEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "gc"}}},
+		}}}
+		objs = append(objs, deploy)
+	}
+	var c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(objs...).Build()
+
+	if err := rolloutWorkloads(context.Background(), c, logr.Discard(), nil, audit.Object{Kind: kindGlobalConfig, Name: "gc"}, "team-a", kindConfigMap, "gc", hash, "unkeyed-hash", false); err != nil {
+		t.Fatal(err)
+	}
+	for name, annotations := range workloads {
+		var deploy = &appsv1.Deployment{}
+		if err := c.Get(context.Background(), types.NamespacedName{Namespace: "team-a", Name: name}, deploy); err != nil {
+			t.Fatal(err)
+		}
+		var want = annotations[key]
+		if name == "outdated" {
+			want = hash
+		}
+		if got := deploy.Spec.Template.Annotations[key]; got != want {
+			t.Errorf("%s: expected the annotation %q, got %q", name, want, got)
+		}
+	}
+}
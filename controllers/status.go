@@ -0,0 +1,187 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	globalsv1beta2 "github.com/jnnkrdb/configrdb/api/v1beta2"
+)
+
+// set the conditions, which describe whether the replicated objects are in sync
+// with the spec of the global object
+//
+// drifted is the number of replicated objects, which are missing, outdated or
+// still exist in namespaces, which have to be avoided
+func setSyncConditions(conditions *[]metav1.Condition, generation int64, suspended bool, drifted int) {
+
+	if suspended {
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               globalsv1beta2.ConditionSuspended,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: generation,
+			Reason:             globalsv1beta2.ReasonSuspended,
+			Message:            "the replication is suspended",
+		})
+	} else {
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               globalsv1beta2.ConditionSuspended,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: generation,
+			Reason:             globalsv1beta2.ReasonActive,
+			Message:            "the replication is active",
+		})
+	}
+
+	switch {
+	case drifted == 0:
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               globalsv1beta2.ConditionSynced,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: generation,
+			Reason:             globalsv1beta2.ReasonSynced,
+			Message:            "all replicated objects are in sync",
+		})
+	case suspended:
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               globalsv1beta2.ConditionSynced,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: generation,
+			Reason:             globalsv1beta2.ReasonSuspended,
+			Message:            fmt.Sprintf("%d replicated objects drifted while the replication is suspended", drifted),
+		})
+	default:
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               globalsv1beta2.ConditionSynced,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: generation,
+			Reason:             globalsv1beta2.ReasonDrifted,
+			Message:            fmt.Sprintf("%d replicated objects drifted", drifted),
+		})
+	}
+}
+
+// set the conditions, which describe whether the namespace regexpressions of a
+// global object are valid, err is the error of the compilation
+func setSelectorCondition(conditions *[]metav1.Condition, generation int64, err error) {
+
+	if err == nil {
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               globalsv1beta2.ConditionInvalidNamespaceSelector,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: generation,
+			Reason:             globalsv1beta2.ReasonValid,
+			Message:            "the namespace regexpressions are valid",
+		})
+		return
+	}
+
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               globalsv1beta2.ConditionInvalidNamespaceSelector,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             globalsv1beta2.ReasonInvalid,
+		Message:            err.Error(),
+	})
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               globalsv1beta2.ConditionSynced,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             globalsv1beta2.ConditionInvalidNamespaceSelector,
+		Message:            "no replicated objects are touched, until the namespace regexpressions are fixed",
+	})
+}
+
+// set the condition, which describes whether a global object violates a
+// globalreplicationpolicy, violations contains the messages of all violations
+func setPolicyCondition(conditions *[]metav1.Condition, generation int64, violations []string) {
+
+	if len(violations) == 0 {
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               globalsv1beta2.ConditionPolicyViolation,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: generation,
+			Reason:             globalsv1beta2.ReasonCompliant,
+			Message:            "the global object complies with all replication policies",
+		})
+		return
+	}
+
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               globalsv1beta2.ConditionPolicyViolation,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             globalsv1beta2.ReasonViolated,
+		Message:            strings.Join(violations, "; "),
+	})
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               globalsv1beta2.ConditionSynced,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             globalsv1beta2.ConditionPolicyViolation,
+		Message:            "no replicated objects are touched, until the policy violations are resolved",
+	})
+}
+
+// set the condition, which describes the planned changes of a global object in dry
+// run mode, the condition is removed, if plan is nil
+func setDryRunCondition(conditions *[]metav1.Condition, generation int64, plan *globalsv1beta2.ReplicationPlan) {
+
+	if plan == nil {
+		meta.RemoveStatusCondition(conditions, globalsv1beta2.ConditionDryRun)
+		return
+	}
+
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               globalsv1beta2.ConditionDryRun,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             globalsv1beta2.ReasonDryRun,
+		Message: fmt.Sprintf("dry run, %d creations, %d updates and %d removals are planned",
+			len(plan.Create), len(plan.Update), len(plan.Delete)),
+	})
+}
+
+// set the condition, which describes whether the matching namespaces contain objects
+// with the name of the global object, which are not owned by it, conflicts contains
+// the namespaces of these objects
+func setConflictCondition(conditions *[]metav1.Condition, generation int64, conflicts []string) {
+
+	if len(conflicts) == 0 {
+		meta.SetStatusCondition(conditions, metav1.Condition{
+			Type:               globalsv1beta2.ConditionConflict,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: generation,
+			Reason:             globalsv1beta2.ReasonNoConflict,
+			Message:            "all replicated objects are owned by the global object",
+		})
+		return
+	}
+
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               globalsv1beta2.ConditionConflict,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: generation,
+		Reason:             globalsv1beta2.ReasonUnowned,
+		Message: fmt.Sprintf("the namespaces %s contain an object with the same name, which is not owned by the global object and is never overwritten",
+			strings.Join(conflicts, ", ")),
+	})
+}
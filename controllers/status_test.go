@@ -0,0 +1,223 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	globalsv1beta2 "github.com/jnnkrdb/configrdb/api/v1beta2"
+)
+
+// check the status, the reason and, if set, a part of the message of a condition
+func expectCondition(t *testing.T, conditions []metav1.Condition, conditionType string, status metav1.ConditionStatus, reason, message string) {
+	t.Helper()
+	var c = meta.FindStatusCondition(conditions, conditionType)
+	switch {
+	case c == nil:
+		t.Errorf("expected the condition %s", conditionType)
+	case c.Status != status || c.Reason != reason || !strings.Contains(c.Message, message):
+		t.Errorf("expected the condition %s to be %s with the reason %s and the message %q, got %s %s %q",
+			conditionType, status, reason, message, c.Status, c.Reason, c.Message)
+	}
+}
+
+func TestSetSyncConditions(t *testing.T) {
+	for _, tt := range []struct {
+		name              string
+		suspended         bool
+		drifted           int
+		suspendedStatus   metav1.ConditionStatus
+		syncedStatus      metav1.ConditionStatus
+		syncedReason      string
+		syncedMessagePart string
+	}{
+		{"active in sync", false, 0, metav1.ConditionFalse, metav1.ConditionTrue, globalsv1beta2.ReasonSynced, "in sync"},
+		{"active drifted", false, 3, metav1.ConditionFalse, metav1.ConditionFalse, globalsv1beta2.ReasonDrifted, "3 replicated objects drifted"},
+		{"suspended in sync", true, 0, metav1.ConditionTrue, metav1.ConditionTrue, globalsv1beta2.ReasonSynced, "in sync"},
+		{"suspended drifted", true, 2, metav1.ConditionTrue, metav1.ConditionFalse, globalsv1beta2.ReasonSuspended, "2 replicated objects drifted while the replication is suspended"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			// the conditions of a former reconciliation are replaced
+			var conditions []metav1.Condition
+			setSyncConditions(&conditions, 1, !tt.suspended, 5)
+			setSyncConditions(&conditions, 2, tt.suspended, tt.drifted)
+
+			var suspendedReason = globalsv1beta2.ReasonActive
+			if tt.suspended {
+				suspendedReason = globalsv1beta2.ReasonSuspended
+			}
+			expectCondition(t, conditions, globalsv1beta2.ConditionSuspended, tt.suspendedStatus, suspendedReason, "")
+			expectCondition(t, conditions, globalsv1beta2.ConditionSynced, tt.syncedStatus, tt.syncedReason, tt.syncedMessagePart)
+			for _, c := range conditions {
+				if c.ObservedGeneration != 2 {
+					t.Errorf("%s: expected the generation 2, got %d", c.Type, c.ObservedGeneration)
+				}
+			}
+		})
+	}
+}
+
+func TestSuspendReportsDrift(t *testing.T) {
+	var ctx = context.Background()
+	var scheme = newPlanScheme(t)
+
+	var gc = &globalsv1beta2.GlobalConfig{}
+	gc.Name, gc.Namespace, gc.UID = "gc", "default", "uid-gc"
+	gc.Finalizers = []string{globalsv1beta2.FinalizerGlobal}
+	gc.Spec.Namespaces = globalsv1beta2.NamespacesRegex{MatchRegex: []string{"^team-"}}
+	gc.Spec.Data = map[string]string{"key": "value"}
+	gc.Spec.Suspend = true
+
+	var c = &writeRecorder{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(append(planNamespaces(), gc)...).Build(), name: gc.Name}
+	var r = &GlobalConfigReconciler{Client: c, Scheme: scheme}
+	var req = ctrl.Request{NamespacedName: types.NamespacedName{Namespace: gc.Namespace, Name: gc.Name}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.created) != 0 || len(c.deleted) != 0 {
+		t.Errorf("expected no writes while suspended, created %q and removed %q", c.created, c.deleted)
+	}
+	if err := c.Get(ctx, req.NamespacedName, gc); err != nil {
+		t.Fatal(err)
+	}
+	// the four team namespaces miss their configmap
+	expectCondition(t, gc.Status.Conditions, globalsv1beta2.ConditionSuspended, metav1.ConditionTrue, globalsv1beta2.ReasonSuspended, "")
+	expectCondition(t, gc.Status.Conditions, globalsv1beta2.ConditionSynced, metav1.ConditionFalse, globalsv1beta2.ReasonSuspended, "4 replicated objects drifted")
+
+	// the data is not replicated, so no revision is recorded
+	if revisions, err := listRevisions(ctx, c, gc); err != nil || len(revisions) != 0 || gc.Status.CurrentRevision != 0 {
+		t.Errorf("expected no revision while suspended, got %d revisions and the current revision %d (%v)", len(revisions), gc.Status.CurrentRevision, err)
+	}
+
+	// the revision is recorded, once the replication is resumed
+	gc.Spec.Suspend = false
+	if err := c.Update(ctx, gc); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if revisions, err := listRevisions(ctx, c, gc); err != nil || len(revisions) != 1 {
+		t.Errorf("expected one revision after resuming, got %d (%v)", len(revisions), err)
+	}
+}
+
+func TestSuspendedGlobalSecretRecordsNoRevision(t *testing.T) {
+	var ctx = context.Background()
+	var scheme = newPlanScheme(t)
+
+	var gs = &globalsv1beta2.GlobalSecret{}
+	gs.Name, gs.Namespace, gs.UID = "gs", "default", "uid-gs"
+	gs.Finalizers = []string{globalsv1beta2.FinalizerGlobal}
+	gs.Spec.Namespaces = globalsv1beta2.NamespacesRegex{MatchRegex: []string{"^team-"}}
+	gs.Spec.Data = globalsv1beta2.SecretData{"key": "dmFsdWU="}
+	gs.Spec.Type = string(v1.SecretTypeOpaque)
+	gs.Spec.Suspend = true
+
+	var c = &writeRecorder{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(append(planNamespaces(), gs)...).Build(), name: gs.Name}
+	var r = &GlobalSecretReconciler{Client: c, Scheme: scheme}
+	var req = ctrl.Request{NamespacedName: types.NamespacedName{Namespace: gs.Namespace, Name: gs.Name}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Get(ctx, req.NamespacedName, gs); err != nil {
+		t.Fatal(err)
+	}
+	if revisions, err := listRevisions(ctx, c, gs); err != nil || len(revisions) != 0 || gs.Status.CurrentRevision != 0 {
+		t.Errorf("expected no revision while suspended, got %d revisions and the current revision %d (%v)", len(revisions), gs.Status.CurrentRevision, err)
+	}
+}
+
+func TestSetSelectorCondition(t *testing.T) {
+	var conditions []metav1.Condition
+	setSyncConditions(&conditions, 1, false, 0)
+
+	// an invalid regexpression marks the global object as out of sync
+	setSelectorCondition(&conditions, 2, errors.New("error parsing regexp: missing closing ): `team-(`"))
+	expectCondition(t, conditions, globalsv1beta2.ConditionInvalidNamespaceSelector, metav1.ConditionTrue, globalsv1beta2.ReasonInvalid, "missing closing )")
+	expectCondition(t, conditions, globalsv1beta2.ConditionSynced, metav1.ConditionFalse, globalsv1beta2.ConditionInvalidNamespaceSelector, "no replicated objects are touched")
+
+	// the fixed regexpressions only reset the selector condition, the sync is reported by the reconciliation
+	setSelectorCondition(&conditions, 3, nil)
+	expectCondition(t, conditions, globalsv1beta2.ConditionInvalidNamespaceSelector, metav1.ConditionFalse, globalsv1beta2.ReasonValid, "")
+	expectCondition(t, conditions, globalsv1beta2.ConditionSynced, metav1.ConditionFalse, globalsv1beta2.ConditionInvalidNamespaceSelector, "")
+}
+
+func TestInvalidSelectorTouchesNothing(t *testing.T) {
+	var ctx = context.Background()
+	var scheme = newPlanScheme(t)
+
+	var gc = &globalsv1beta2.GlobalConfig{}
+	gc.Name, gc.Namespace, gc.UID, gc.Generation = "gc", "default", "uid-gc", 2
+	gc.Finalizers = []string{globalsv1beta2.FinalizerGlobal}
+	gc.Spec.Namespaces = globalsv1beta2.NamespacesRegex{MatchRegex: []string{"team-("}}
+	gc.Spec.Data = map[string]string{"key": "value"}
+
+	// the copy of the former generation must not be removed
+	var cm = &v1.ConfigMap{}
+	cm.Name, cm.Namespace = gc.Name, "team-a"
+	cm.Labels = globalsv1beta2.Labels(kindGlobalConfig, gc)
+	cm.Annotations = globalsv1beta2.Annotations("outdated", gc)
+
+	var c = &writeRecorder{Client: fake.NewClientBuilder().WithScheme(scheme).WithObjects(append(planNamespaces(), gc, cm)...).Build(), name: gc.Name}
+	var r = &GlobalConfigReconciler{Client: c, Scheme: scheme}
+	var req = ctrl.Request{NamespacedName: types.NamespacedName{Namespace: gc.Namespace, Name: gc.Name}}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.created) != 0 || len(c.deleted) != 0 {
+		t.Errorf("expected no writes with an invalid regex, created %q and removed %q", c.created, c.deleted)
+	}
+	if err := c.Get(ctx, req.NamespacedName, gc); err != nil {
+		t.Fatal(err)
+	}
+	expectCondition(t, gc.Status.Conditions, globalsv1beta2.ConditionInvalidNamespaceSelector, metav1.ConditionTrue, globalsv1beta2.ReasonInvalid, "team-(")
+	expectCondition(t, gc.Status.Conditions, globalsv1beta2.ConditionSynced, metav1.ConditionFalse, globalsv1beta2.ConditionInvalidNamespaceSelector, "")
+	if c := meta.FindStatusCondition(gc.Status.Conditions, globalsv1beta2.ConditionInvalidNamespaceSelector); c != nil && c.ObservedGeneration != 2 {
+		t.Errorf("expected the generation 2, got %d", c.ObservedGeneration)
+	}
+
+	// an invalid pattern of a wave is reported the same way, instead of being retried
+	gc.Spec.Namespaces.MatchRegex = []string{"^team-"}
+	gc.Spec.RolloutStrategy = &globalsv1beta2.RolloutStrategy{Waves: []globalsv1beta2.RolloutWave{{Name: "broken", MatchRegex: []string{"("}}}}
+	if err := c.Update(ctx, gc); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := r.Reconcile(ctx, req); err != nil || res.Requeue || res.RequeueAfter != 0 {
+		t.Fatalf("expected no retry of an invalid wave, got %+v (%v)", res, err)
+	}
+	if len(c.created) != 0 || len(c.deleted) != 0 {
+		t.Errorf("expected no writes with an invalid wave, created %q and removed %q", c.created, c.deleted)
+	}
+	if err := c.Get(ctx, req.NamespacedName, gc); err != nil {
+		t.Fatal(err)
+	}
+	expectCondition(t, gc.Status.Conditions, globalsv1beta2.ConditionInvalidNamespaceSelector, metav1.ConditionTrue, globalsv1beta2.ReasonInvalid, "wave \"broken\"")
+}
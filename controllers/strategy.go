@@ -0,0 +1,145 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	globalsv1beta2 "github.com/jnnkrdb/configrdb/api/v1beta2"
+)
+
+// get the time to wait between two batches of the same wave, a strategy, which was
+// not defaulted by the webhook, waits for the default interval
+func rolloutBatchInterval(strategy *globalsv1beta2.RolloutStrategy) time.Duration {
+	if strategy.BatchInterval == nil || strategy.BatchInterval.Duration <= 0 {
+		return globalsv1beta2.DefaultRolloutBatchInterval
+	}
+	return strategy.BatchInterval.Duration
+}
+
+// plan the next step of a staged rollout
+//
+// the outdated namespaces are grouped into the waves of the strategy, whose patterns
+// were compiled by [globalsv1beta2.RolloutStrategy.Compile], only the
+// next batch of the first wave with outdated namespaces is updated, all other
+// outdated namespaces are returned as deferred
+//
+// the progress is stored in the given status, including the earliest time of the
+// next batch and the next wave, so a reconciliation, which is triggered by another
+// event, does not start them earlier, requeueAfter is zero, if nothing is deferred
+func stageRollout(strategy *globalsv1beta2.RolloutStrategy, waveRegex [][]*regexp.Regexp, status *globalsv1beta2.RolloutStatus, hash string, outdated []string, total int, now time.Time) (deferred map[string]bool, requeueAfter time.Duration) {
+
+	// a new content hash restarts the rollout with the first wave
+	if status.ContentHash != hash {
+		*status = globalsv1beta2.RolloutStatus{ContentHash: hash}
+	}
+	status.TotalNamespaces = int32(total)
+	status.UpdatedNamespaces = int32(total - len(outdated))
+
+	// group the outdated namespaces into the waves, the last
+	// wave contains the namespaces, which match no wave
+	var waves = make([][]string, len(strategy.Waves)+1)
+	for _, ns := range outdated {
+		var wave = len(strategy.Waves)
+	find:
+		for i := range waveRegex {
+			for _, re := range waveRegex[i] {
+				if re.MatchString(ns) {
+					wave = i
+					break find
+				}
+			}
+		}
+		waves[wave] = append(waves[wave], ns)
+	}
+
+	// the first wave with outdated namespaces is the current wave
+	var current = -1
+	for i := range waves {
+		if len(waves[i]) > 0 {
+			current = i
+			break
+		}
+	}
+	if current < 0 {
+		status.PausedUntil = nil
+		status.NextBatchAfter = nil
+		return nil, 0
+	}
+	status.CurrentWave = int32(current)
+	status.CurrentWaveName = "default"
+	if current < len(strategy.Waves) {
+		status.CurrentWaveName = strategy.Waves[current].Name
+	}
+
+	deferred = make(map[string]bool, len(outdated))
+	for _, ns := range outdated {
+		deferred[ns] = true
+	}
+
+	// wait for the pause between two waves and the interval between two batches
+	if status.PausedUntil != nil && now.Before(status.PausedUntil.Time) {
+		return deferred, status.PausedUntil.Sub(now)
+	}
+	status.PausedUntil = nil
+	if status.NextBatchAfter != nil && now.Before(status.NextBatchAfter.Time) {
+		return deferred, status.NextBatchAfter.Sub(now)
+	}
+	status.NextBatchAfter = nil
+
+	// select the next batch of the current wave
+	var batch = waves[current]
+	sort.Strings(batch)
+	if strategy.BatchSize > 0 && int(strategy.BatchSize) < len(batch) {
+		batch = batch[:strategy.BatchSize]
+	}
+	for _, ns := range batch {
+		delete(deferred, ns)
+	}
+	status.UpdatedNamespaces += int32(len(batch))
+
+	if len(deferred) == 0 {
+		return nil, 0
+	}
+
+	// if this batch finishes the current wave, the next wave has to wait
+	if len(batch) == len(waves[current]) && strategy.PauseBetweenWaves.Duration > 0 {
+		status.PausedUntil = &metav1.Time{Time: now.Add(strategy.PauseBetweenWaves.Duration)}
+		return deferred, strategy.PauseBetweenWaves.Duration
+	}
+	var interval = rolloutBatchInterval(strategy)
+	status.NextBatchAfter = &metav1.Time{Time: now.Add(interval)}
+	return deferred, interval
+}
+
+// set the synced condition of a global object, whose staged rollout is in progress
+func setRolloutCondition(conditions *[]metav1.Condition, generation int64, status *globalsv1beta2.RolloutStatus) {
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               globalsv1beta2.ConditionSynced,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: generation,
+		Reason:             globalsv1beta2.ReasonRolling,
+		Message: fmt.Sprintf("rolling out wave %d (%s), %d of %d namespaces updated",
+			status.CurrentWave, status.CurrentWaveName, status.UpdatedNamespaces, status.TotalNamespaces),
+	})
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	globalsv1beta2 "github.com/jnnkrdb/configrdb/api/v1beta2"
+)
+
+func TestStageRollout(t *testing.T) {
+	var now = time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	var at = func(d time.Duration) *metav1.Time { return &metav1.Time{Time: now.Add(d)} }
+
+	var canary = []globalsv1beta2.RolloutWave{{Name: "canary", MatchRegex: []string{"^canary-"}}}
+
+	for _, tc := range []struct {
+		name         string
+		strategy     globalsv1beta2.RolloutStrategy
+		status       globalsv1beta2.RolloutStatus
+		outdated     []string
+		deferred     []string
+		requeueAfter time.Duration
+		wave         string
+		updated      int32
+		pausedUntil  *metav1.Time
+		nextBatch    *metav1.Time
+	}{
+		{
+			name:     "the first wave is updated first",
+			strategy: globalsv1beta2.RolloutStrategy{Waves: canary},
+			outdated: []string{"canary-1", "prod-1", "prod-2"},
+			deferred: []string{"prod-1", "prod-2"}, requeueAfter: globalsv1beta2.DefaultRolloutBatchInterval,
+			wave: "canary", updated: 3, nextBatch: at(globalsv1beta2.DefaultRolloutBatchInterval),
+		},
+		{
+			name:     "the namespaces without wave are updated last",
+			strategy: globalsv1beta2.RolloutStrategy{Waves: canary},
+			status:   globalsv1beta2.RolloutStatus{ContentHash: "hash"},
+			outdated: []string{"prod-1", "prod-2"},
+			wave:     "default", updated: 5,
+		},
+		{
+			name:     "a wave is updated in batches",
+			strategy: globalsv1beta2.RolloutStrategy{BatchSize: 2},
+			outdated: []string{"c", "a", "b"},
+			deferred: []string{"c"}, requeueAfter: globalsv1beta2.DefaultRolloutBatchInterval,
+			wave: "default", updated: 4, nextBatch: at(globalsv1beta2.DefaultRolloutBatchInterval),
+		},
+		{
+			name:     "the next batch waits for the interval",
+			strategy: globalsv1beta2.RolloutStrategy{BatchSize: 2},
+			status:   globalsv1beta2.RolloutStatus{ContentHash: "hash", NextBatchAfter: at(3 * time.Second)},
+			outdated: []string{"c"},
+			deferred: []string{"c"}, requeueAfter: 3 * time.Second,
+			wave: "default", updated: 4, nextBatch: at(3 * time.Second),
+		},
+		{
+			name:     "the next batch starts after the interval",
+			strategy: globalsv1beta2.RolloutStrategy{BatchSize: 2},
+			status:   globalsv1beta2.RolloutStatus{ContentHash: "hash", NextBatchAfter: at(-time.Second)},
+			outdated: []string{"c"},
+			wave:     "default", updated: 5,
+		},
+		{
+			name:     "the last batch of a wave starts the pause",
+			strategy: globalsv1beta2.RolloutStrategy{Waves: canary, PauseBetweenWaves: metav1.Duration{Duration: time.Minute}},
+			outdated: []string{"canary-1", "prod-1"},
+			deferred: []string{"prod-1"}, requeueAfter: time.Minute,
+			wave: "canary", updated: 4, pausedUntil: at(time.Minute),
+		},
+		{
+			name:     "the next wave waits for the pause",
+			strategy: globalsv1beta2.RolloutStrategy{Waves: canary, PauseBetweenWaves: metav1.Duration{Duration: time.Minute}},
+			status:   globalsv1beta2.RolloutStatus{ContentHash: "hash", PausedUntil: at(30 * time.Second)},
+			outdated: []string{"prod-1"},
+			deferred: []string{"prod-1"}, requeueAfter: 30 * time.Second,
+			wave: "default", updated: 4, pausedUntil: at(30 * time.Second),
+		},
+		{
+			name:     "a new hash restarts the rollout",
+			strategy: globalsv1beta2.RolloutStrategy{Waves: canary, BatchSize: 1, PauseBetweenWaves: metav1.Duration{Duration: time.Minute}},
+			status:   globalsv1beta2.RolloutStatus{ContentHash: "former", CurrentWave: 1, PausedUntil: at(time.Minute), NextBatchAfter: at(time.Minute)},
+			outdated: []string{"canary-1", "canary-2", "prod-1"},
+			deferred: []string{"canary-2", "prod-1"}, requeueAfter: globalsv1beta2.DefaultRolloutBatchInterval,
+			wave: "canary", updated: 3, nextBatch: at(globalsv1beta2.DefaultRolloutBatchInterval),
+		},
+		{
+			name:     "the batches wait for the interval of the strategy",
+			strategy: globalsv1beta2.RolloutStrategy{BatchSize: 1, BatchInterval: &metav1.Duration{Duration: time.Minute}},
+			outdated: []string{"a", "b"},
+			deferred: []string{"b"}, requeueAfter: time.Minute,
+			wave: "default", updated: 4, nextBatch: at(time.Minute),
+		},
+		{
+			name: "the waves use the match mode of the strategy",
+			strategy: globalsv1beta2.RolloutStrategy{MatchMode: globalsv1beta2.MatchModeGlob, Waves: []globalsv1beta2.RolloutWave{
+				{Name: "canary", MatchRegex: []string{"canary-*"}},
+			}},
+			outdated: []string{"canary-1", "x-canary-1"},
+			deferred: []string{"x-canary-1"}, requeueAfter: globalsv1beta2.DefaultRolloutBatchInterval,
+			wave: "canary", updated: 4, nextBatch: at(globalsv1beta2.DefaultRolloutBatchInterval),
+		},
+		{
+			name:     "a finished rollout clears the pauses",
+			strategy: globalsv1beta2.RolloutStrategy{Waves: canary},
+			status:   globalsv1beta2.RolloutStatus{ContentHash: "hash", PausedUntil: at(time.Minute), NextBatchAfter: at(time.Minute)},
+			updated:  5,
+		},
+	} {
+		var status = tc.status
+		waves, err := tc.strategy.Compile()
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		deferred, requeueAfter := stageRollout(&tc.strategy, waves, &status, "hash", tc.outdated, 5, now)
+
+		var names []string
+		for ns := range deferred {
+			names = append(names, ns)
+		}
+		sort.Strings(names)
+		if fmt.Sprint(names) != fmt.Sprint(tc.deferred) || requeueAfter != tc.requeueAfter {
+			t.Errorf("%s: expected %q deferred for %s, got %q for %s", tc.name, tc.deferred, tc.requeueAfter, names, requeueAfter)
+		}
+		if status.ContentHash != "hash" || status.CurrentWaveName != tc.wave || status.UpdatedNamespaces != tc.updated || status.TotalNamespaces != 5 {
+			t.Errorf("%s: unexpected status %+v", tc.name, status)
+		}
+		if !status.PausedUntil.Equal(tc.pausedUntil) || !status.NextBatchAfter.Equal(tc.nextBatch) {
+			t.Errorf("%s: expected the pause %v and the next batch %v, got %v and %v", tc.name, tc.pausedUntil, tc.nextBatch, status.PausedUntil, status.NextBatchAfter)
+		}
+	}
+}
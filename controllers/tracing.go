@@ -0,0 +1,92 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	globalsv1beta2 "github.com/jnnkrdb/configrdb/api/v1beta2"
+	"github.com/jnnkrdb/configrdb/internal/audit"
+	"github.com/jnnkrdb/configrdb/internal/tracing"
+)
+
+// trace a reconciliation of a global object, the outcome is requeue, if the
+// global object is reconciled again without an error
+func traceReconcile(ctx context.Context, kind string, req ctrl.Request, reconcile func(context.Context, ctrl.Request) (ctrl.Result, error)) (ctrl.Result, error) {
+	ctx, span := tracing.Start(ctx, kind+".Reconcile", tracing.Global(kind, req.NamespacedName)...)
+
+	res, err := reconcile(ctx, req)
+
+	var outcome = tracing.OutcomeSuccess
+	if res.Requeue || res.RequeueAfter > 0 {
+		outcome = tracing.OutcomeRequeue
+	}
+	tracing.End(span, outcome, err)
+	return res, err
+}
+
+// trace the calculation of the matching and avoided namespaces of a global object
+func traceCalculateNamespaces(ctx context.Context, _log logr.Logger, c client.Client, kind string, key types.NamespacedName, nsr *globalsv1beta2.CompiledNamespacesRegex) ([]v1.Namespace, []v1.Namespace, error) {
+	ctx, span := tracing.Start(ctx, "CalculateNamespaces", tracing.Global(kind, key)...)
+
+	matches, avoids, err := nsr.CalculateNamespaces(_log, ctx, c)
+
+	span.SetAttributes(tracing.AttributeMatched.Int(len(matches)), tracing.AttributeAvoided.Int(len(avoids)))
+	tracing.End(span, tracing.OutcomeSuccess, err)
+	return matches, avoids, err
+}
+
+// trace and audit a write of a replicated object in a target namespace, the hash is
+// the content hash of the replicated object after the write or before its removal
+func recordWrite(ctx context.Context, a *audit.Auditor, kind string, parent client.Object, action audit.Action, namespace, hash string, write func(context.Context) error) error {
+	var attrs = append(tracing.Global(kind, client.ObjectKeyFromObject(parent)), tracing.AttributeTarget.String(namespace), tracing.AttributeAction.String(string(action)))
+	ctx, span := tracing.Start(ctx, kind+"."+string(action), attrs...)
+
+	var err = write(ctx)
+
+	tracing.End(span, tracing.OutcomeSuccess, err)
+
+	var rec = audit.Record{
+		Action:      action,
+		Outcome:     audit.OutcomeSuccess,
+		Parent:      audit.ObjectOf(kind, parent),
+		Target:      audit.Object{Kind: replicaKind(kind), Namespace: namespace, Name: parent.GetName()},
+		ContentHash: hash,
+	}
+	if err != nil {
+		rec.Outcome = audit.OutcomeError
+	}
+	if aErr := a.Record(ctx, rec); aErr != nil {
+		log.FromContext(ctx).Error(aErr, "error recording the change", "action", action, "namespace", namespace)
+	}
+	return err
+}
+
+// get the kind of the replicated objects of a global object
+func replicaKind(kind string) string {
+	if kind == kindGlobalSecret {
+		return kindSecret
+	}
+	return kindConfigMap
+}
@@ -0,0 +1,286 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package audit records every change of the operator to the cluster as a line
+// of json, the records of a replica are chained with their hmacs, so a removed,
+// reordered or modified record is detected by Verify
+//
+// the hmacs and the content hashes of the records are keyed with a secret key, so
+// the chain can not be forged without the key and the data of a secret can not be
+// guessed from its content hash, the records never contain the data itself
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// the changes, which are recorded
+type Action string
+
+const (
+	// a replicated object was created
+	ActionCreate Action = "create"
+
+	// a replicated object was replaced with the new data
+	ActionUpdate Action = "update"
+
+	// a replicated object was removed
+	ActionDelete Action = "delete"
+
+	// the labels and annotations of a replicated object were patched
+	ActionPatch Action = "patch"
+
+	// a consuming workload was restarted
+	ActionRestart Action = "restart"
+)
+
+// how the change ended, the error itself is not recorded, it may contain the data
+const (
+	OutcomeSuccess = "success"
+	OutcomeError   = "error"
+)
+
+// an object of the cluster
+type Object struct {
+	Kind       string    `json:"kind"`
+	Namespace  string    `json:"namespace,omitempty"`
+	Name       string    `json:"name"`
+	UID        types.UID `json:"uid,omitempty"`
+	Generation int64     `json:"generation,omitempty"`
+}
+
+// get the object of the record
+func ObjectOf(kind string, obj client.Object) Object {
+	return Object{
+		Kind:       kind,
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+		UID:        obj.GetUID(),
+		Generation: obj.GetGeneration(),
+	}
+}
+
+// a recorded change
+type Record struct {
+	// the time of the change
+	Time time.Time `json:"time"`
+
+	// the replica of the operator, which made the change
+	Actor string `json:"actor"`
+
+	// the change and how it ended
+	Action  Action `json:"action"`
+	Outcome string `json:"outcome"`
+
+	// the global object and its generation, which caused the change, the parent
+	// of an orphan only has a kind, namespace and name
+	Parent Object `json:"parent"`
+
+	// the changed object
+	Target Object `json:"target"`
+
+	// the content hash of the data of the target after the change, or before
+	// the removal, see ContentHash in api/v1beta2, the auditor keys the content
+	// hash with its key, see KeyContentHash
+	ContentHash string `json:"contentHash,omitempty"`
+
+	// the number of the record, counted per actor
+	Sequence uint64 `json:"seq"`
+
+	// the hash of the former record of the actor
+	Previous string `json:"prev"`
+
+	// the hmac-sha256 of the record with an empty hash
+	Hash string `json:"hash"`
+}
+
+// calculate the hash of a record
+func (rec Record) calculateHash(key []byte) (string, error) {
+	rec.Hash = ""
+	raw, err := json.Marshal(rec)
+	if err != nil {
+		return "", err
+	}
+	return mac(key, raw), nil
+}
+
+// the hmac-sha256 of the data, hex encoded
+func mac(key, data []byte) string {
+	var h = hmac.New(sha256.New, key)
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// key the content hash of an object, e.g. of its annotation, like the auditor keys
+// the content hashes of the records, so the records of an object can be found
+func KeyContentHash(key []byte, hash string) string {
+	if hash == "" {
+		return ""
+	}
+	return mac(key, []byte(hash))
+}
+
+// the minimum length of the key of the auditor
+const MinKeyLength = 32
+
+// the key in the data of the secret, which holds the key of the auditor
+const SecretKey = "key"
+
+// read the key of the auditor from a secret
+func KeyFromSecret(ctx context.Context, r client.Reader, namespace, name string) ([]byte, error) {
+	var secret = &v1.Secret{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, secret); err != nil {
+		return nil, err
+	}
+	var key = secret.Data[SecretKey]
+	if len(key) < MinKeyLength {
+		return nil, fmt.Errorf("the key %q of the secret %s/%s must have at least %d bytes", SecretKey, namespace, name, MinKeyLength)
+	}
+	return key, nil
+}
+
+// the destination of the records, every record is written as a single line of json
+type Sink interface {
+	Write(ctx context.Context, line []byte) error
+}
+
+// the auditor chains the records and writes them to the sinks
+//
+// a nil auditor does not record anything
+type Auditor struct {
+	actor string
+	key   []byte
+	sinks []Sink
+
+	mu       sync.Mutex
+	sequence uint64
+	last     string
+}
+
+// get a new auditor, the actor identifies the replica in the records, the key
+// signs the chain, see KeyFromSecret
+func New(actor string, key []byte, sinks ...Sink) *Auditor {
+	return &Auditor{actor: actor, key: key, sinks: sinks}
+}
+
+// continue the chain of a former run of the same actor, e.g. after a restart
+func (a *Auditor) Resume(last *Record) {
+	if last == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.sequence, a.last = last.Sequence, last.Hash
+}
+
+// record a change, the time, the actor and the chain are set by the auditor
+//
+// the record is written to all sinks, even if one of them fails
+func (a *Auditor) Record(ctx context.Context, rec Record) error {
+	if a == nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	rec.Time = time.Now().UTC()
+	rec.Actor = a.actor
+	rec.Sequence = a.sequence + 1
+	rec.Previous = a.last
+	rec.ContentHash = KeyContentHash(a.key, rec.ContentHash)
+
+	var err error
+	if rec.Hash, err = rec.calculateHash(a.key); err != nil {
+		return err
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	a.sequence, a.last = rec.Sequence, rec.Hash
+
+	var errs []error
+	for _, sink := range a.sinks {
+		if err = sink.Write(ctx, line); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// verify the chains of the records with the key of the auditor, every actor has
+// its own chain
+//
+// the lines, which are no records, e.g. the logs of the operator, are skipped,
+// the first record of an actor may follow a removed record, because the ring
+// buffer drops the oldest records, the number of verified records is returned
+func Verify(r io.Reader, key []byte) (int, error) {
+
+	var last = make(map[string]Record)
+	var verified int
+
+	var scanner = bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil || rec.Hash == "" || rec.Sequence == 0 {
+			continue
+		}
+
+		hash, err := rec.calculateHash(key)
+		if err != nil {
+			return verified, err
+		}
+		if hash != rec.Hash {
+			return verified, fmt.Errorf("line %d: the record %d of %s was modified or signed with another key", line, rec.Sequence, rec.Actor)
+		}
+		if former, ok := last[rec.Actor]; ok && (rec.Previous != former.Hash || rec.Sequence != former.Sequence+1) {
+			return verified, fmt.Errorf("line %d: the record %d of %s does not follow the record %d, records were removed or reordered",
+				line, rec.Sequence, rec.Actor, former.Sequence)
+		}
+		last[rec.Actor] = rec
+		verified++
+	}
+	return verified, scanner.Err()
+}
+
+// get the last record of an actor in the lines
+func lastRecord(raw []byte, actor string) *Record {
+	var lines = bytes.Split(bytes.TrimSpace(raw), []byte("\n"))
+	for i := len(lines) - 1; i >= 0; i-- {
+		var rec Record
+		if err := json.Unmarshal(lines[i], &rec); err == nil && rec.Hash != "" && rec.Actor == actor {
+			return &rec
+		}
+	}
+	return nil
+}
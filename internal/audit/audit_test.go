@@ -0,0 +1,209 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+var testRecord = Record{
+	Action:      ActionCreate,
+	Outcome:     OutcomeSuccess,
+	Parent:      Object{Kind: "GlobalSecret", Namespace: "default", Name: "gs", UID: "gs-uid", Generation: 3},
+	Target:      Object{Kind: "Secret", Namespace: "team-a", Name: "gs"},
+	ContentHash: "abc",
+}
+
+func TestVerify(t *testing.T) {
+	var buf = &bytes.Buffer{}
+	var a = New("confrdb-0", testKey, NewWriterSink(buf))
+	for i := 0; i < 3; i++ {
+		if err := a.Record(context.Background(), testRecord); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	// the logs of the operator between the records are skipped
+	var intact = strings.Join([]string{lines[0], `{"level":"info","msg":"reconciling"}`, lines[1], lines[2]}, "\n")
+	if n, err := Verify(strings.NewReader(intact), testKey); err != nil || n != 3 {
+		t.Errorf("expected 3 verified records, got %d (%v)", n, err)
+	}
+
+	// the ring buffer drops the first records
+	if n, err := Verify(strings.NewReader(lines[2]), testKey); err != nil || n != 1 {
+		t.Errorf("expected 1 verified record, got %d (%v)", n, err)
+	}
+
+	for name, tampered := range map[string]string{
+		"modified":  strings.Join([]string{lines[0], strings.Replace(lines[1], `"team-a"`, `"team-b"`, 1), lines[2]}, "\n"),
+		"removed":   strings.Join([]string{lines[0], lines[2]}, "\n"),
+		"reordered": strings.Join([]string{lines[1], lines[0], lines[2]}, "\n"),
+	} {
+		if _, err := Verify(strings.NewReader(tampered), testKey); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	// the chain can not be verified or forged without the key
+	if _, err := Verify(strings.NewReader(intact), []byte("another-key-0123456789abcdef0123")); err == nil {
+		t.Error("expected an error with another key")
+	}
+
+	// the content hash is keyed
+	var rec Record
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.ContentHash == testRecord.ContentHash || rec.ContentHash != KeyContentHash(testKey, testRecord.ContentHash) {
+		t.Errorf("expected the keyed content hash, got %s", rec.ContentHash)
+	}
+}
+
+func TestOpenFile(t *testing.T) {
+	var path = filepath.Join(t.TempDir(), "audit.jsonl")
+
+	sink, last, err := OpenFile(path, "confrdb-0")
+	if err != nil || last != nil {
+		t.Fatalf("expected an empty file, got %v (%v)", last, err)
+	}
+	if err = New("confrdb-0", testKey, sink).Record(context.Background(), testRecord); err != nil {
+		t.Fatal(err)
+	}
+
+	// the chain is resumed after a restart
+	sink, last, err = OpenFile(path, "confrdb-0")
+	if err != nil || last == nil || last.Sequence != 1 {
+		t.Fatalf("expected the first record, got %v (%v)", last, err)
+	}
+	var a = New("confrdb-0", testKey, sink)
+	a.Resume(last)
+	if err = a.Record(context.Background(), testRecord); err != nil {
+		t.Fatal(err)
+	}
+
+	_, last, _ = OpenFile(path, "confrdb-0")
+	if last.Sequence != 2 {
+		t.Errorf("expected the second record, got %d", last.Sequence)
+	}
+}
+
+func TestConfigMapSink(t *testing.T) {
+	var scheme = runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	var c = fake.NewClientBuilder().WithScheme(scheme).Build()
+
+	var failing = &failingClient{Client: c, fail: true}
+	var sink = &ConfigMapSink{Reader: c, Client: failing, Namespace: "confrdb-system", Name: "confrdb-audit", Size: 2}
+	var a = New("confrdb-0", testKey, sink)
+	for i := 0; i < 3; i++ {
+		if err := a.Record(context.Background(), testRecord); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the records are buffered until the flush, which is retried after an error
+	var cm = &v1.ConfigMap{}
+	var key = types.NamespacedName{Namespace: sink.Namespace, Name: sink.Name}
+	if err := c.Get(context.Background(), key, cm); !apierrors.IsNotFound(err) {
+		t.Fatalf("expected no configmap before the flush, got %v", err)
+	}
+	if err := sink.Flush(context.Background()); err == nil {
+		t.Fatal("expected the error of the client")
+	}
+	failing.fail = false
+	if err := sink.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Get(context.Background(), key, cm); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := Verify(strings.NewReader(cm.Data[ConfigMapKey]), testKey); err != nil || n != 2 {
+		t.Errorf("expected the 2 latest records, got %d (%v)", n, err)
+	}
+
+	if last, err := sink.Last(context.Background(), "confrdb-0"); err != nil || last == nil || last.Sequence != 3 {
+		t.Errorf("expected the third record, got %v (%v)", last, err)
+	}
+
+	// the records of another replica are appended in one update
+	var other = New("confrdb-1", testKey, sink)
+	for i := 0; i < 2; i++ {
+		if err := other.Record(context.Background(), testRecord); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := sink.Flush(context.Background()); err != nil || failing.updates != 1 {
+		t.Fatalf("expected a single update, got %d (%v)", failing.updates, err)
+	}
+}
+
+// a client, whose writes fail until fail is unset, the updates are counted
+type failingClient struct {
+	client.Client
+	fail    bool
+	updates int
+}
+
+func (c *failingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if c.fail {
+		return errors.New("unavailable")
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *failingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if c.fail {
+		return errors.New("unavailable")
+	}
+	c.updates++
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func TestKeyFromSecret(t *testing.T) {
+	var scheme = runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+
+	var short, valid = &v1.Secret{}, &v1.Secret{}
+	short.Namespace, short.Name, short.Data = "confrdb-system", "short", map[string][]byte{SecretKey: []byte("short")}
+	valid.Namespace, valid.Name, valid.Data = "confrdb-system", "valid", map[string][]byte{SecretKey: testKey}
+	var c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(short, valid).Build()
+
+	for name, wantErr := range map[string]bool{"valid": false, "short": true, "missing": true} {
+		key, err := KeyFromSecret(context.Background(), c, "confrdb-system", name)
+		if (err != nil) != wantErr || (!wantErr && !bytes.Equal(key, testKey)) {
+			t.Errorf("%s: unexpected key %q (%v)", name, key, err)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package audit
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// the number of bytes at the end of an audit file, which are searched for the
+// last record, when the file is opened again
+const resumeWindow = 1024 * 1024
+
+// a sink, which appends the records to a writer, e.g. stdout or a file
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// get a sink, which appends the records to the writer
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// Write implements Sink
+func (s *WriterSink) Write(_ context.Context, line []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.w.Write(append(line, '\n'))
+	return err
+}
+
+// open a file, which the records are appended to, the file is created, if it does
+// not exist, the last record of the actor in the file is returned, so its chain
+// can be resumed, see Auditor.Resume
+func OpenFile(path, actor string) (*WriterSink, *Record, error) {
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	var offset int64
+	if info.Size() > resumeWindow {
+		offset = info.Size() - resumeWindow
+	}
+	var tail = make([]byte, info.Size()-offset)
+	if _, err = f.ReadAt(tail, offset); err != nil && err != io.EOF {
+		f.Close()
+		return nil, nil, err
+	}
+	return NewWriterSink(f), lastRecord(tail, actor), nil
+}
+
+// the key of the records in the configmap of the ConfigMapSink
+const ConfigMapKey = "audit.jsonl"
+
+// a sink, which keeps the latest records in a configmap, the oldest records
+// are dropped, when the configmap holds more records than its size
+//
+// the records are buffered and written in batches by Start, so the reconciliations
+// do not wait for the configmap and the replicas, which share the configmap, e.g.
+// with the sharding, update it once per interval instead of once per record
+type ConfigMapSink struct {
+	// the reader is used to read the configmap, it should not be cached, so the
+	// configmap can be in a namespace, which is not cached by the manager
+	Reader client.Reader
+
+	// the client is used to create and update the configmap
+	Client client.Client
+
+	Namespace string
+	Name      string
+
+	// the number of records, which are kept
+	Size int
+
+	// the interval, in which the buffered records are written
+	FlushInterval time.Duration
+
+	Log logr.Logger
+
+	mu      sync.Mutex
+	pending []string
+}
+
+// Write implements Sink, the record is buffered until the next flush, the oldest
+// records are dropped, when more records than the size are buffered
+func (s *ConfigMapSink) Write(_ context.Context, line []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.buffer([]string{string(line)})
+	return nil
+}
+
+// append the lines to the buffered records, the lock must be held
+func (s *ConfigMapSink) buffer(lines []string) {
+	s.pending = append(s.pending, lines...)
+	if len(s.pending) > s.Size {
+		s.pending = s.pending[len(s.pending)-s.Size:]
+	}
+}
+
+// Start implements manager.Runnable, the buffered records are written every
+// interval and once more, when the manager stops
+func (s *ConfigMapSink) Start(ctx context.Context) error {
+	var ticker = time.NewTicker(s.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.Flush(ctx); err != nil {
+				s.Log.Error(err, "unable to write the audit records, retrying", "configmap", s.Name)
+			}
+		case <-ctx.Done():
+			// the context of the manager is already done
+			flushCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := s.Flush(flushCtx); err != nil {
+				s.Log.Error(err, "unable to write the last audit records", "configmap", s.Name)
+			}
+			return nil
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, every replica
+// writes its own records
+func (s *ConfigMapSink) NeedLeaderElection() bool {
+	return false
+}
+
+// write the buffered records to the configmap, the records stay buffered, if they
+// could not be written, so they are retried with the next flush
+func (s *ConfigMapSink) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	var lines = s.pending
+	s.pending = nil
+	s.mu.Unlock()
+
+	if len(lines) == 0 {
+		return nil
+	}
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+
+		var cm = &v1.ConfigMap{}
+		err := s.Reader.Get(ctx, types.NamespacedName{Namespace: s.Namespace, Name: s.Name}, cm)
+		if apierrors.IsNotFound(err) {
+			cm.Namespace, cm.Name = s.Namespace, s.Name
+			cm.Data = map[string]string{ConfigMapKey: strings.Join(lines, "\n") + "\n"}
+			return s.Client.Create(ctx, cm)
+		}
+		if err != nil {
+			return err
+		}
+
+		var all = strings.Split(strings.TrimSpace(cm.Data[ConfigMapKey]), "\n")
+		if all[0] == "" {
+			all = all[:0]
+		}
+		all = append(all, lines...)
+		if len(all) > s.Size {
+			all = all[len(all)-s.Size:]
+		}
+
+		if cm.Data == nil {
+			cm.Data = make(map[string]string, 1)
+		}
+		cm.Data[ConfigMapKey] = strings.Join(all, "\n") + "\n"
+		return s.Client.Update(ctx, cm)
+	})
+	if err != nil {
+		// keep the order of the records, the records of the meantime follow
+		s.mu.Lock()
+		var meantime = s.pending
+		s.pending = nil
+		s.buffer(append(lines, meantime...))
+		s.mu.Unlock()
+	}
+	return err
+}
+
+// get the last record of the actor in the configmap, so its chain can be resumed
+func (s *ConfigMapSink) Last(ctx context.Context, actor string) (*Record, error) {
+	var cm = &v1.ConfigMap{}
+	if err := s.Reader.Get(ctx, types.NamespacedName{Namespace: s.Namespace, Name: s.Name}, cm); err != nil {
+		return nil, client.IgnoreNotFound(err)
+	}
+	return lastRecord([]byte(cm.Data[ConfigMapKey]), actor), nil
+}
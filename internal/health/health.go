@@ -0,0 +1,46 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package health contains the checks of the probes of the manager
+package health
+
+import (
+	"errors"
+	"net/http"
+
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+// an informer of the cache of the manager
+type Informer interface {
+	HasSynced() bool
+}
+
+// get a readiness check, which fails, until the informers have synced, so the pod
+// is not ready, before the events of their kinds are received
+//
+// the informers of the global objects must not be checked, they are converted by the
+// conversion webhook of the same pod, which is only reachable, once the pod is ready
+func InformersSynced(informers ...Informer) healthz.Checker {
+	return func(*http.Request) error {
+		for _, informer := range informers {
+			if !informer.HasSynced() {
+				return errors.New("the informers have not synced yet")
+			}
+		}
+		return nil
+	}
+}
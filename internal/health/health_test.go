@@ -0,0 +1,45 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+// an informer, which has synced, once synced is set
+type fakeInformer struct {
+	synced bool
+}
+
+func (i *fakeInformer) HasSynced() bool {
+	return i.synced
+}
+
+func TestInformersSynced(t *testing.T) {
+	var namespaces, configMaps = &fakeInformer{synced: true}, &fakeInformer{}
+	var check = InformersSynced(namespaces, configMaps)
+
+	if err := check(httptest.NewRequest("GET", "/readyz", nil)); err == nil {
+		t.Error("expected the check to fail before all informers synced")
+	}
+
+	configMaps.synced = true
+	if err := check(httptest.NewRequest("GET", "/readyz", nil)); err != nil {
+		t.Errorf("expected the check to pass after the informers synced, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package keys provides the secret keys of the operator, which are shared by all
+// replicas through a secret in the namespace of the operator
+package keys
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// the key in the data of the secret, which holds the key
+const SecretKey = "key"
+
+// the length of the generated keys, shorter keys are rejected
+const Length = 32
+
+// get the key of a secret, the secret is created with a random key, if it does not
+// exist yet, so the replicas share the key, which one of them created
+func Ensure(ctx context.Context, r client.Reader, c client.Client, namespace, name string) ([]byte, error) {
+
+	var key = types.NamespacedName{Namespace: namespace, Name: name}
+	var secret = &v1.Secret{}
+	err := r.Get(ctx, key, secret)
+	if apierrors.IsNotFound(err) {
+		var random = make([]byte, Length)
+		if _, err = rand.Read(random); err != nil {
+			return nil, err
+		}
+		secret.Namespace, secret.Name = namespace, name
+		secret.Data = map[string][]byte{SecretKey: random}
+		if err = c.Create(ctx, secret); err == nil {
+			return random, nil
+		}
+		if !apierrors.IsAlreadyExists(err) {
+			return nil, err
+		}
+		// another replica created the key in the meantime
+		secret = &v1.Secret{}
+		err = r.Get(ctx, key, secret)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(secret.Data[SecretKey]) < Length {
+		return nil, fmt.Errorf("the key %q of the secret %s must have at least %d bytes", SecretKey, key, Length)
+	}
+	return secret.Data[SecretKey], nil
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package keys
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// a client, whose secret was created by another replica right before the create
+type racingClient struct {
+	client.Client
+	other []byte
+}
+
+func (c *racingClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	var secret = obj.(*v1.Secret).DeepCopy()
+	secret.Data = map[string][]byte{SecretKey: c.other}
+	if err := c.Client.Create(ctx, secret, opts...); err != nil {
+		return err
+	}
+	return apierrors.NewAlreadyExists(schema.GroupResource{Resource: "secrets"}, obj.GetName())
+}
+
+func TestEnsure(t *testing.T) {
+	var ctx = context.Background()
+	var scheme = runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+
+	var short = &v1.Secret{}
+	short.Namespace, short.Name, short.Data = "confrdb-system", "short", map[string][]byte{SecretKey: []byte("short")}
+	var c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(short).Build()
+
+	// the key is created once and read afterwards
+	created, err := Ensure(ctx, c, c, "confrdb-system", "confrdb-content-hash")
+	if err != nil || len(created) != Length {
+		t.Fatalf("expected a new key, got %d bytes (%v)", len(created), err)
+	}
+	read, err := Ensure(ctx, c, c, "confrdb-system", "confrdb-content-hash")
+	if err != nil || !bytes.Equal(created, read) {
+		t.Errorf("expected the same key, got %v", err)
+	}
+
+	// the key of another replica wins
+	var other = bytes.Repeat([]byte("o"), Length)
+	raced, err := Ensure(ctx, c, &racingClient{Client: c, other: other}, "confrdb-system", "raced")
+	if err != nil || !bytes.Equal(raced, other) {
+		t.Errorf("expected the key of the other replica, got %q (%v)", raced, err)
+	}
+
+	if _, err = Ensure(ctx, c, c, "confrdb-system", "short"); err == nil {
+		t.Error("expected an error for a short key")
+	}
+}
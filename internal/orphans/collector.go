@@ -0,0 +1,166 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package orphans
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	globalsv1beta2 "github.com/jnnkrdb/configrdb/api/v1beta2"
+	"github.com/jnnkrdb/configrdb/internal/audit"
+)
+
+// what happens with the orphans, which are found by the collector
+type Policy string
+
+const (
+	// the orphans are deleted
+	PolicyDelete Policy = "delete"
+
+	// the orphans are only logged
+	PolicyReport Policy = "report"
+
+	// the collector does not run at all
+	PolicyIgnore Policy = "ignore"
+)
+
+// parse the policy of the collector
+func ParsePolicy(policy string) (Policy, error) {
+	switch p := Policy(policy); p {
+	case PolicyDelete, PolicyReport, PolicyIgnore:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown orphan policy %q, expected delete, report or ignore", policy)
+}
+
+// the collector searches for orphans at the start of the manager and
+// afterwards in the given interval, and handles them according to the policy
+type Collector struct {
+	// the reader is used to find the orphans, it should not be cached, so the manager
+	// does not need to cache all configmaps and secrets of the cluster
+	Reader client.Reader
+
+	// the client is used to delete the orphans
+	Client client.Client
+
+	// the namespaces, which are searched for orphans
+	Scope Scope
+
+	// the audit of the removed orphans, nil records nothing
+	Audit *audit.Auditor
+
+	Policy   Policy
+	Interval time.Duration
+	Log      logr.Logger
+}
+
+var _ manager.Runnable = &Collector{}
+var _ manager.LeaderElectionRunnable = &Collector{}
+
+// Start implements manager.Runnable
+func (col *Collector) Start(ctx context.Context) error {
+
+	if col.Policy == PolicyIgnore {
+		return nil
+	}
+
+	col.collect(ctx)
+	if col.Interval <= 0 {
+		return nil
+	}
+
+	var ticker = time.NewTicker(col.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			col.collect(ctx)
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, only the
+// leader deletes the orphans
+func (col *Collector) NeedLeaderElection() bool {
+	return true
+}
+
+// find the orphans and handle them according to the policy, the errors are
+// only logged, so the next run can try again
+func (col *Collector) collect(ctx context.Context) {
+
+	found, err := Find(ctx, col.Reader, col.Scope)
+	if err != nil {
+		col.Log.Error(err, "error searching for orphans")
+		return
+	}
+
+	for _, obj := range found {
+		var objLog = col.Log.WithValues("kind", Kind(obj), "namespace", obj.GetNamespace(), "name", obj.GetName())
+
+		if col.Policy == PolicyReport {
+			objLog.Info("found orphan")
+			continue
+		}
+
+		// the global object could have been created after the search
+		if ok, err := adopted(ctx, col.Reader, obj); err != nil || ok {
+			if err != nil {
+				objLog.Error(err, "error receiving the global object of the orphan")
+			}
+			continue
+		}
+
+		// the precondition keeps a replicated object, which was recreated with the same name
+		objLog.Info("removing orphan")
+		var uid = obj.GetUID()
+		if err = col.Client.Delete(ctx, obj, client.Preconditions{UID: &uid}); client.IgnoreNotFound(err) != nil {
+			objLog.Error(err, "error removing orphan")
+		}
+		if aErr := col.Audit.Record(ctx, deletion(obj, err)); aErr != nil {
+			objLog.Error(aErr, "error recording the removal")
+		}
+	}
+}
+
+// get the audit record of a removed orphan, the global object of the orphan
+// does not exist anymore, so the parent is read from its labels and annotations
+func deletion(obj client.Object, err error) audit.Record {
+
+	var rec = audit.Record{
+		Action:      audit.ActionDelete,
+		Outcome:     audit.OutcomeSuccess,
+		Parent:      audit.Object{UID: types.UID(obj.GetLabels()[globalsv1beta2.LabelUID])},
+		Target:      audit.ObjectOf(Kind(obj), obj),
+		ContentHash: obj.GetAnnotations()[globalsv1beta2.AnnotationContentHash],
+	}
+	if kind, key, ok := globalsv1beta2.ParentOf(obj); ok {
+		rec.Parent.Kind, rec.Parent.Namespace, rec.Parent.Name = kind, key.Namespace, key.Name
+	}
+	if client.IgnoreNotFound(err) != nil {
+		rec.Outcome = audit.OutcomeError
+	}
+	return rec
+}
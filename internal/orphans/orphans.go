@@ -0,0 +1,165 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package orphans finds the replicated configmaps and secrets, whose global
+// object does not exist anymore
+package orphans
+
+import (
+	"context"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	globalsv1beta2 "github.com/jnnkrdb/configrdb/api/v1beta2"
+)
+
+// the namespaces, which are searched for orphans, an empty list stands for all namespaces
+type Scope struct {
+	// the namespaces of the global objects
+	WatchNamespaces []string
+
+	// the namespaces of the replicated configmaps and secrets
+	TargetNamespaces []string
+}
+
+// find the replicated configmaps and secrets, whose labels point to the uid of a
+// globalconfig or globalsecret, which does not exist anymore
+//
+// this happens, if a global object is force-deleted by removing its finalizer
+// or if the customresourcedefinitions are reinstalled
+//
+// the replicated objects are listed before the global objects, a global object is
+// always created before its replicated objects, so a replicated object, which is
+// created during the search, always finds its global object in the second list
+//
+// if the scope is restricted to watched namespaces, only the replicated objects,
+// whose parent label points to one of these namespaces, can be orphans, since the
+// global objects of the other namespaces are not listed
+func Find(ctx context.Context, c client.Reader, scope Scope) ([]client.Object, error) {
+
+	var configMaps []v1.ConfigMap
+	var secrets []v1.Secret
+	for _, ns := range namespaces(scope.TargetNamespaces) {
+
+		var configMapList = &v1.ConfigMapList{}
+		if err := c.List(ctx, configMapList, client.InNamespace(ns), client.HasLabels{globalsv1beta2.LabelUID}); err != nil {
+			return nil, err
+		}
+		configMaps = append(configMaps, configMapList.Items...)
+
+		var secretList = &v1.SecretList{}
+		if err := c.List(ctx, secretList, client.InNamespace(ns), client.HasLabels{globalsv1beta2.LabelUID}); err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, secretList.Items...)
+	}
+
+	var configUIDs = make(map[types.UID]bool)
+	var secretUIDs = make(map[types.UID]bool)
+	for _, ns := range namespaces(scope.WatchNamespaces) {
+
+		var configs = &globalsv1beta2.GlobalConfigList{}
+		if err := c.List(ctx, configs, client.InNamespace(ns)); err != nil {
+			return nil, err
+		}
+		for i := range configs.Items {
+			configUIDs[configs.Items[i].UID] = true
+		}
+
+		var globalSecrets = &globalsv1beta2.GlobalSecretList{}
+		if err := c.List(ctx, globalSecrets, client.InNamespace(ns)); err != nil {
+			return nil, err
+		}
+		for i := range globalSecrets.Items {
+			secretUIDs[globalSecrets.Items[i].UID] = true
+		}
+	}
+
+	var orphans []client.Object
+	for i := range configMaps {
+		if scope.covers(&configMaps[i]) && !configUIDs[types.UID(configMaps[i].Labels[globalsv1beta2.LabelUID])] {
+			orphans = append(orphans, &configMaps[i])
+		}
+	}
+	for i := range secrets {
+		if scope.covers(&secrets[i]) && !secretUIDs[types.UID(secrets[i].Labels[globalsv1beta2.LabelUID])] {
+			orphans = append(orphans, &secrets[i])
+		}
+	}
+
+	return orphans, nil
+}
+
+// check again, whether the global object of an orphan exists, right before the orphan
+// is removed, the global object is read by the parent annotations of the orphan, the
+// orphans of former versions without these annotations are never adopted
+func adopted(ctx context.Context, c client.Reader, obj client.Object) (bool, error) {
+
+	kind, key, ok := globalsv1beta2.ParentOf(obj)
+	if !ok {
+		return false, nil
+	}
+
+	var parent client.Object
+	switch {
+	case kind == "GlobalConfig" && Kind(obj) == "ConfigMap":
+		parent = &globalsv1beta2.GlobalConfig{}
+	case kind == "GlobalSecret" && Kind(obj) == "Secret":
+		parent = &globalsv1beta2.GlobalSecret{}
+	default:
+		return false, nil
+	}
+
+	if err := c.Get(ctx, key, parent); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+	return string(parent.GetUID()) == obj.GetLabels()[globalsv1beta2.LabelUID], nil
+}
+
+// check, whether the parent of a replicated object is in one of the watched namespaces
+func (scope Scope) covers(obj client.Object) bool {
+	if len(scope.WatchNamespaces) == 0 {
+		return true
+	}
+	var parent = obj.GetLabels()[globalsv1beta2.LabelParentNamespace]
+	for _, ns := range scope.WatchNamespaces {
+		if ns == parent {
+			return true
+		}
+	}
+	return false
+}
+
+// get the namespaces to list, an empty namespace lists all namespaces
+func namespaces(list []string) []string {
+	if len(list) == 0 {
+		return []string{""}
+	}
+	return list
+}
+
+// get the kind of an orphan
+func Kind(obj client.Object) string {
+	switch obj.(type) {
+	case *v1.ConfigMap:
+		return "ConfigMap"
+	case *v1.Secret:
+		return "Secret"
+	}
+	return ""
+}
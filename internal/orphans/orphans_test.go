@@ -0,0 +1,197 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package orphans
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/go-logr/logr"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	globalsv1beta2 "github.com/jnnkrdb/configrdb/api/v1beta2"
+	"github.com/jnnkrdb/configrdb/internal/audit"
+)
+
+func TestFind(t *testing.T) {
+	var scheme = runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = globalsv1beta2.AddToScheme(scheme)
+
+	var gc = &globalsv1beta2.GlobalConfig{}
+	gc.Name, gc.Namespace, gc.UID = "gc", "default", "gc-uid"
+	var gs = &globalsv1beta2.GlobalSecret{}
+	gs.Name, gs.Namespace, gs.UID = "gs", "default", "gs-uid"
+
+	var configMap = func(name string, uid types.UID) *v1.ConfigMap {
+		var cm = &v1.ConfigMap{}
+		cm.Name, cm.Namespace, cm.Labels = name, "team-a", globalsv1beta2.MatchingLables(uid)
+		return cm
+	}
+	var secret = func(name string, uid types.UID) *v1.Secret {
+		var s = &v1.Secret{}
+		s.Name, s.Namespace, s.Labels = name, "team-a", globalsv1beta2.MatchingLables(uid)
+		return s
+	}
+	var unrelated = &v1.ConfigMap{}
+	unrelated.Name, unrelated.Namespace = "unrelated", "team-a"
+
+	var c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		gc, gs, unrelated,
+		configMap("gc", "gc-uid"), configMap("dead-gc", "dead-uid"),
+		// a configmap can not belong to a globalsecret
+		configMap("gs", "gs-uid"),
+		secret("gs", "gs-uid"), secret("dead-gs", "dead-uid"),
+	).Build()
+
+	orphans, err := Find(context.Background(), c, Scope{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var found = make(map[string]bool)
+	for _, obj := range orphans {
+		found[Kind(obj)+"/"+obj.GetName()] = true
+	}
+	for _, want := range []string{"ConfigMap/dead-gc", "ConfigMap/gs", "Secret/dead-gs"} {
+		if !found[want] {
+			t.Errorf("expected the orphan %s, got %v", want, found)
+		}
+	}
+	if len(orphans) != 3 {
+		t.Errorf("expected 3 orphans, got %v", found)
+	}
+
+	// a restricted scope only reports the orphans of the watched namespaces
+	var scoped = secret("scoped", "scoped-uid")
+	scoped.Labels = globalsv1beta2.Labels("GlobalSecret", gs)
+	scoped.Labels[globalsv1beta2.LabelUID] = "scoped-uid"
+	if err = c.Create(context.Background(), scoped); err != nil {
+		t.Fatal(err)
+	}
+	orphans, err = Find(context.Background(), c, Scope{WatchNamespaces: []string{"default"}, TargetNamespaces: []string{"team-a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orphans) != 1 || orphans[0].GetName() != "scoped" {
+		t.Errorf("expected only the orphan scoped, got %v", orphans)
+	}
+}
+
+// a reader, which creates a global object and its configmap, while the configmaps
+// are listed, and hides the global objects from the lists, if hide is set
+type racingReader struct {
+	client.Client
+	created bool
+	hide    bool
+}
+
+func (r *racingReader) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	if _, ok := list.(*v1.ConfigMapList); ok && !r.created {
+		r.created = true
+		var gc = &globalsv1beta2.GlobalConfig{}
+		gc.Name, gc.Namespace, gc.UID = "new", "default", "new-uid"
+		var cm = &v1.ConfigMap{}
+		cm.Name, cm.Namespace, cm.Labels = "new", "team-a", globalsv1beta2.Labels("GlobalConfig", gc)
+		cm.Annotations = map[string]string{globalsv1beta2.AnnotationParentName: gc.Name}
+		if err := r.Client.Create(ctx, gc); err != nil {
+			return err
+		}
+		if err := r.Client.Create(ctx, cm); err != nil {
+			return err
+		}
+	}
+	if _, ok := list.(*globalsv1beta2.GlobalConfigList); ok && r.hide {
+		return nil
+	}
+	return r.Client.List(ctx, list, opts...)
+}
+
+func TestFindRace(t *testing.T) {
+	var scheme = runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = globalsv1beta2.AddToScheme(scheme)
+
+	// a global object, which is created during the search, has no orphans
+	var r = &racingReader{Client: fake.NewClientBuilder().WithScheme(scheme).Build()}
+	orphans, err := Find(context.Background(), r, Scope{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orphans) != 0 {
+		t.Errorf("expected no orphans, got %v", orphans)
+	}
+
+	// the collector reads the global object again, before an orphan is removed
+	r = &racingReader{Client: fake.NewClientBuilder().WithScheme(scheme).Build(), hide: true}
+	var col = &Collector{Reader: r, Client: r.Client, Policy: PolicyDelete, Log: logr.Discard()}
+	if err = col.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var cm = &v1.ConfigMap{}
+	if err = r.Get(context.Background(), types.NamespacedName{Namespace: "team-a", Name: "new"}, cm); err != nil {
+		t.Errorf("the configmap of the new global object was removed: %v", err)
+	}
+}
+
+func TestCollector(t *testing.T) {
+	var scheme = runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = globalsv1beta2.AddToScheme(scheme)
+
+	for _, tc := range []struct {
+		policy    Policy
+		remaining int
+		audited   int
+	}{
+		{PolicyReport, 1, 0},
+		{PolicyDelete, 0, 1},
+		{PolicyIgnore, 1, 0},
+	} {
+		var orphan = &v1.ConfigMap{}
+		orphan.Name, orphan.Namespace, orphan.Labels = "dead-gc", "team-a", globalsv1beta2.MatchingLables("dead-uid")
+		var c = fake.NewClientBuilder().WithScheme(scheme).WithObjects(orphan).Build()
+
+		var records = &bytes.Buffer{}
+		var key = []byte("0123456789abcdef0123456789abcdef")
+		var col = &Collector{Reader: c, Client: c, Audit: audit.New("confrdb-0", key, audit.NewWriterSink(records)), Policy: tc.policy, Log: logr.Discard()}
+		if err := col.Start(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		var list = &v1.ConfigMapList{}
+		if err := c.List(context.Background(), list); err != nil {
+			t.Fatal(err)
+		}
+		if len(list.Items) != tc.remaining {
+			t.Errorf("%s: expected %d configmaps, got %d", tc.policy, tc.remaining, len(list.Items))
+		}
+		if n, err := audit.Verify(records, key); err != nil || n != tc.audited {
+			t.Errorf("%s: expected %d audit records, got %d (%v)", tc.policy, tc.audited, n, err)
+		}
+	}
+
+	if _, err := ParsePolicy("keep"); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+}
@@ -0,0 +1,416 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sharding splits the global objects between the replicas of the
+// operator, the replicas claim the shards with leases and each replica only
+// reconciles the global objects, whose uid hashes to one of its shards
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// the label of the leases, which are used for the sharding, the value is
+// either [roleMember] or [roleShard]
+const LabelSharding = "globals.jnnkrdb.de/sharding"
+
+const (
+	// every replica renews a member lease, so the other replicas know the
+	// number of replicas and their fair share of the shards
+	roleMember = "member"
+
+	// every shard has a lease, which is held by the replica owning the shard
+	roleShard = "shard"
+)
+
+// get the shard of a global object
+func ShardOf(uid types.UID, shards int) int {
+	var h = fnv.New32a()
+	h.Write([]byte(uid))
+	return int(h.Sum32() % uint32(shards))
+}
+
+// the sharder claims and renews the leases of the shards of one replica
+//
+// every replica claims its fair share of the shards, which is the number of
+// shards divided by the number of live replicas, rounded up, a replica, which
+// holds more shards, releases the surplus, so the shards are rebalanced,
+// whenever replicas come and go
+type Sharder struct {
+	// the reader is used to read the leases, it should not be cached, so the
+	// manager does not need to watch the leases
+	Reader client.Reader
+
+	// the client is used to create and update the leases
+	Client client.Client
+
+	// the namespace of the leases, usually the namespace of the operator
+	Namespace string
+
+	// the unique name of the replica, usually the name of the pod
+	Identity string
+
+	// the number of shards
+	Shards int
+
+	// the duration, after which a lease, which was not renewed, expires
+	LeaseDuration time.Duration
+
+	// the interval, in which the leases are renewed, must be shorter than the lease duration
+	RenewInterval time.Duration
+
+	Log logr.Logger
+
+	mu          sync.RWMutex
+	owned       map[int]bool
+	expires     time.Time
+	subscribers []chan event.GenericEvent
+
+	// the clock of the sharder, replaced in the tests
+	now func() time.Time
+}
+
+var _ manager.Runnable = &Sharder{}
+var _ manager.LeaderElectionRunnable = &Sharder{}
+
+// Subscribe returns a channel, which receives an event, whenever the shards of
+// the replica change, so all global objects can be enqueued again
+//
+// Subscribe must be called before the manager is started
+func (s *Sharder) Subscribe() <-chan event.GenericEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ch = make(chan event.GenericEvent, 1)
+	s.subscribers = append(s.subscribers, ch)
+	return ch
+}
+
+// Owns checks, whether the global object with the uid belongs to one of the
+// shards of the replica
+//
+// the shards are no longer owned, if the leases could not be renewed in time,
+// since another replica may claim them afterwards
+func (s *Sharder) Owns(uid types.UID) bool {
+	return s.ownsShard(ShardOf(uid, s.Shards))
+}
+
+// check, whether the replica owns the shard and its lease did not expire
+func (s *Sharder) ownsShard(shard int) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.owned[shard] && s.clock().Before(s.expires)
+}
+
+// Start implements manager.Runnable
+func (s *Sharder) Start(ctx context.Context) error {
+
+	var ticker = time.NewTicker(s.RenewInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.sync(ctx); err != nil {
+			s.Log.Error(err, "error syncing the shards")
+		}
+
+		select {
+		case <-ctx.Done():
+			// hand over the shards, so the other replicas do not have to wait for the leases to expire
+			releaseCtx, cancel := context.WithTimeout(context.Background(), s.RenewInterval)
+			defer cancel()
+			return s.release(releaseCtx)
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, every
+// replica claims its own shards
+func (s *Sharder) NeedLeaderElection() bool {
+	return false
+}
+
+// renew the member lease, release the surplus shards and claim the missing shards
+func (s *Sharder) sync(ctx context.Context) error {
+
+	var now = s.clock()
+	var member = s.memberLease()
+	if err := s.Reader.Get(ctx, client.ObjectKeyFromObject(member), member); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	if err := s.renew(ctx, member, now); err != nil {
+		return fmt.Errorf("renewing the member lease: %w", err)
+	}
+
+	var members = &coordinationv1.LeaseList{}
+	if err := s.Reader.List(ctx, members, client.InNamespace(s.Namespace), client.MatchingLabels{LabelSharding: roleMember}); err != nil {
+		return err
+	}
+	var live = 0
+	for i := range members.Items {
+		if !expired(&members.Items[i], now) {
+			live++
+		}
+	}
+	if live == 0 {
+		// the own member lease is not listed yet
+		live = 1
+	}
+	var fair = (s.Shards + live - 1) / live
+
+	var shards = &coordinationv1.LeaseList{}
+	if err := s.Reader.List(ctx, shards, client.InNamespace(s.Namespace), client.MatchingLabels{LabelSharding: roleShard}); err != nil {
+		return err
+	}
+	var leases = make(map[int]*coordinationv1.Lease, len(shards.Items))
+	for i := range shards.Items {
+		if shard, ok := s.shardOf(&shards.Items[i]); ok {
+			leases[shard] = &shards.Items[i]
+		}
+	}
+
+	// the shards, which are held by this replica, in ascending order
+	var held []int
+	for shard, lease := range leases {
+		if holder(lease) == s.Identity && !expired(lease, now) {
+			held = append(held, shard)
+		}
+	}
+	sort.Ints(held)
+
+	// the surplus shards are dropped from the owned shards, before their leases are
+	// released, so the replica stops reconciling them, before another replica claims them
+	if len(held) > fair {
+		s.disown(held[fair:])
+		for _, shard := range held[fair:] {
+			if err := s.releaseLease(ctx, leases[shard]); err != nil {
+				s.Log.Error(err, "error releasing a shard", "shard", shard)
+			}
+		}
+		held = held[:fair]
+	}
+
+	var owned = make(map[int]bool, fair)
+	for _, shard := range held {
+		if err := s.renew(ctx, leases[shard], now); err != nil {
+			s.Log.Error(err, "error renewing a shard", "shard", shard)
+			continue
+		}
+		owned[shard] = true
+	}
+
+	// claim the free shards and the shards, whose holders did not renew them in time
+	for shard := 0; shard < s.Shards && len(owned) < fair; shard++ {
+		if owned[shard] {
+			continue
+		}
+		var lease, exists = leases[shard]
+		if exists && holder(lease) != "" && !expired(lease, now) {
+			continue
+		}
+		if !exists {
+			lease = s.shardLease(shard)
+		}
+		if err := s.renew(ctx, lease, now); err != nil {
+			// another replica was faster
+			if !apierrors.IsConflict(err) && !apierrors.IsAlreadyExists(err) {
+				s.Log.Error(err, "error claiming a shard", "shard", shard)
+			}
+			continue
+		}
+		owned[shard] = true
+	}
+
+	s.update(owned, now.Add(s.LeaseDuration))
+	return nil
+}
+
+// hold the lease for another lease duration, the lease is created, if it does not exist
+//
+// the resource version of the lease prevents, that two replicas claim the same shard
+func (s *Sharder) renew(ctx context.Context, lease *coordinationv1.Lease, now time.Time) error {
+
+	var renewTime = metav1.NewMicroTime(now)
+	var duration = int32(s.LeaseDuration.Seconds())
+
+	if holder(lease) != s.Identity {
+		var transitions int32
+		if lease.Spec.LeaseTransitions != nil {
+			transitions = *lease.Spec.LeaseTransitions + 1
+		}
+		lease.Spec.HolderIdentity = &s.Identity
+		lease.Spec.AcquireTime = &renewTime
+		lease.Spec.LeaseTransitions = &transitions
+	}
+	lease.Spec.RenewTime = &renewTime
+	lease.Spec.LeaseDurationSeconds = &duration
+
+	if lease.ResourceVersion == "" {
+		return s.Client.Create(ctx, lease)
+	}
+	return s.Client.Update(ctx, lease)
+}
+
+// give up a shard, so another replica can claim it
+func (s *Sharder) releaseLease(ctx context.Context, lease *coordinationv1.Lease) error {
+	lease.Spec.HolderIdentity = nil
+	lease.Spec.RenewTime = nil
+	lease.Spec.AcquireTime = nil
+	return s.Client.Update(ctx, lease)
+}
+
+// release all shards and remove the member lease, when the replica stops
+func (s *Sharder) release(ctx context.Context) error {
+
+	s.update(nil, time.Time{})
+
+	var shards = &coordinationv1.LeaseList{}
+	if err := s.Reader.List(ctx, shards, client.InNamespace(s.Namespace), client.MatchingLabels{LabelSharding: roleShard}); err != nil {
+		return err
+	}
+	for i := range shards.Items {
+		if holder(&shards.Items[i]) == s.Identity {
+			if err := s.releaseLease(ctx, &shards.Items[i]); err != nil {
+				s.Log.Error(err, "error releasing a shard", "lease", shards.Items[i].Name)
+			}
+		}
+	}
+	return client.IgnoreNotFound(s.Client.Delete(ctx, s.memberLease()))
+}
+
+// store the owned shards and notify the subscribers, if they changed
+func (s *Sharder) update(owned map[int]bool, expires time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.expires = expires
+	if len(owned) == len(s.owned) {
+		var changed = false
+		for shard := range owned {
+			changed = changed || !s.owned[shard]
+		}
+		if !changed {
+			return
+		}
+	}
+	s.owned = owned
+	s.notify()
+}
+
+// drop the shards from the owned shards and notify the subscribers, the expiry
+// of the other shards is kept
+func (s *Sharder) disown(shards []int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var owned = make(map[int]bool, len(s.owned))
+	for shard := range s.owned {
+		owned[shard] = true
+	}
+	for _, shard := range shards {
+		delete(owned, shard)
+	}
+	if len(owned) == len(s.owned) {
+		return
+	}
+	s.owned = owned
+	s.notify()
+}
+
+// log the owned shards and notify the subscribers, the lock must be held
+func (s *Sharder) notify() {
+
+	var shards = make([]int, 0, len(s.owned))
+	for shard := range s.owned {
+		shards = append(shards, shard)
+	}
+	sort.Ints(shards)
+	s.Log.Info("the owned shards changed", "shards", shards, "total", s.Shards)
+
+	for _, ch := range s.subscribers {
+		select {
+		case ch <- event.GenericEvent{Object: s.memberLease()}:
+		default:
+			// an event is already pending
+		}
+	}
+}
+
+func (s *Sharder) memberLease() *coordinationv1.Lease {
+	var lease = &coordinationv1.Lease{}
+	lease.Name = "confrdb-member-" + s.Identity
+	lease.Namespace = s.Namespace
+	lease.Labels = map[string]string{LabelSharding: roleMember}
+	return lease
+}
+
+func (s *Sharder) shardLease(shard int) *coordinationv1.Lease {
+	var lease = &coordinationv1.Lease{}
+	lease.Name = "confrdb-shard-" + strconv.Itoa(shard)
+	lease.Namespace = s.Namespace
+	lease.Labels = map[string]string{LabelSharding: roleShard}
+	return lease
+}
+
+// get the shard of a shard lease, the leases of the shards, which exceed the
+// configured number of shards, are ignored
+func (s *Sharder) shardOf(lease *coordinationv1.Lease) (int, bool) {
+	if !strings.HasPrefix(lease.Name, "confrdb-shard-") {
+		return 0, false
+	}
+	var shard, err = strconv.Atoi(strings.TrimPrefix(lease.Name, "confrdb-shard-"))
+	return shard, err == nil && shard >= 0 && shard < s.Shards
+}
+
+func (s *Sharder) clock() time.Time {
+	if s.now != nil {
+		return s.now()
+	}
+	return time.Now()
+}
+
+// get the identity of the holder of a lease
+func holder(lease *coordinationv1.Lease) string {
+	if lease.Spec.HolderIdentity == nil {
+		return ""
+	}
+	return *lease.Spec.HolderIdentity
+}
+
+// check, whether the holder of a lease did not renew it in time
+func expired(lease *coordinationv1.Lease, now time.Time) bool {
+	if lease.Spec.RenewTime == nil || lease.Spec.LeaseDurationSeconds == nil {
+		return true
+	}
+	return now.After(lease.Spec.RenewTime.Add(time.Duration(*lease.Spec.LeaseDurationSeconds) * time.Second))
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newSharder(c client.Client, identity string, now func() time.Time) *Sharder {
+	return &Sharder{
+		Reader:        c,
+		Client:        c,
+		Namespace:     "confrdb",
+		Identity:      identity,
+		Shards:        4,
+		LeaseDuration: 15 * time.Second,
+		RenewInterval: 5 * time.Second,
+		Log:           logr.Discard(),
+		now:           now,
+	}
+}
+
+// get the shards, which are owned by a sharder
+func ownedShards(s *Sharder) map[int]bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var owned = make(map[int]bool, len(s.owned))
+	for shard := range s.owned {
+		owned[shard] = true
+	}
+	return owned
+}
+
+// a client, which checks, that a released shard is no longer owned by the sharder
+type releaseChecker struct {
+	client.Client
+	t       *testing.T
+	sharder *Sharder
+}
+
+func (c *releaseChecker) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if lease, ok := obj.(*coordinationv1.Lease); ok && lease.Spec.HolderIdentity == nil {
+		if shard, ok := c.sharder.shardOf(lease); ok && ownedShards(c.sharder)[shard] {
+			c.t.Errorf("the shard %d is released, while it is still owned", shard)
+		}
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func TestSharder(t *testing.T) {
+	var ctx = context.Background()
+	var c = fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build()
+
+	var now = time.Now()
+	var clock = func() time.Time { return now }
+	var a, b = newSharder(c, "a", clock), newSharder(c, "b", clock)
+	a.Client = &releaseChecker{Client: c, t: t, sharder: a}
+	var changes = a.Subscribe()
+
+	// a single replica claims all shards
+	if err := a.sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(ownedShards(a)) != 4 {
+		t.Fatalf("expected a to own all shards, got %v", ownedShards(a))
+	}
+	select {
+	case <-changes:
+	default:
+		t.Error("expected an event for the changed shards")
+	}
+
+	// the second replica joins, a releases the surplus, b claims it
+	for _, s := range []*Sharder{b, a, b} {
+		if err := s.sync(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var ownedA, ownedB = ownedShards(a), ownedShards(b)
+	if len(ownedA) != 2 || len(ownedB) != 2 {
+		t.Fatalf("expected 2 shards each, got %v and %v", ownedA, ownedB)
+	}
+	for shard := range ownedA {
+		if ownedB[shard] {
+			t.Errorf("shard %d is owned by both replicas", shard)
+		}
+	}
+
+	// every global object is owned by exactly one replica
+	for _, uid := range []types.UID{"uid-1", "uid-2", "uid-3", "uid-4", "uid-5"} {
+		if a.Owns(uid) == b.Owns(uid) {
+			t.Errorf("the object %s must be owned by exactly one replica", uid)
+		}
+	}
+
+	// b stops renewing, its leases expire and a claims all shards again
+	now = now.Add(20 * time.Second)
+	if b.Owns("uid-1") || b.Owns("uid-2") || b.Owns("uid-3") {
+		t.Error("b must not own any object after its leases expired")
+	}
+	if err := a.sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(ownedShards(a)) != 4 {
+		t.Errorf("expected a to own all shards again, got %v", ownedShards(a))
+	}
+
+	// a stops and hands over its shards
+	if err := a.release(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(ownedShards(b)) != 4 {
+		t.Errorf("expected b to own all shards after a stopped, got %v", ownedShards(b))
+	}
+}
+
+// a runnable, which reports its starts and stops
+type blockingRunnable struct {
+	started, stopped chan struct{}
+}
+
+func (r *blockingRunnable) Start(ctx context.Context) error {
+	r.started <- struct{}{}
+	<-ctx.Done()
+	r.stopped <- struct{}{}
+	return nil
+}
+
+func TestSingleton(t *testing.T) {
+	var c = fake.NewClientBuilder().WithScheme(clientgoscheme.Scheme).Build()
+	var s = newSharder(c, "a", time.Now)
+	var r = &blockingRunnable{started: make(chan struct{}, 1), stopped: make(chan struct{}, 1)}
+	var sg = s.Singleton(r)
+
+	var expect = func(ch chan struct{}, what string) {
+		t.Helper()
+		select {
+		case <-ch:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("the singleton was not %s", what)
+		}
+	}
+
+	var ctx, cancel = context.WithCancel(context.Background())
+	var done = make(chan error, 1)
+	go func() { done <- sg.Start(ctx) }()
+
+	// the singleton starts with the shard 0 and stops, when the shard is lost
+	if err := s.sync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	expect(r.started, "started")
+	s.disown([]int{0})
+	expect(r.stopped, "stopped")
+
+	// the singleton starts again with the shard and stops with the manager
+	s.update(map[int]bool{0: true}, time.Now().Add(time.Minute))
+	expect(r.started, "started again")
+	cancel()
+	expect(r.stopped, "stopped with the manager")
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if sg.(*singleton).NeedLeaderElection() {
+		t.Error("the singleton must not need the leader election")
+	}
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"context"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// the shard, whose replica runs the singletons
+const singletonShard = 0
+
+// a runnable, which runs on a single replica, see Sharder.Singleton
+type singleton struct {
+	sharder  *Sharder
+	runnable manager.Runnable
+	changes  <-chan event.GenericEvent
+}
+
+var _ manager.Runnable = &singleton{}
+var _ manager.LeaderElectionRunnable = &singleton{}
+
+// Singleton wraps a runnable, which must only run on a single replica, e.g. the
+// orphan collector, since the sharding replaces the leader election
+//
+// the runnable runs on the replica, which owns the shard 0, it is stopped, when the
+// replica loses the shard, and started again on the replica, which claims it, a
+// runnable, which returns on its own, is only started again after the shard moved
+//
+// Singleton must be called before the manager is started
+func (s *Sharder) Singleton(r manager.Runnable) manager.Runnable {
+	return &singleton{sharder: s, runnable: r, changes: s.Subscribe()}
+}
+
+// a running singleton
+type run struct {
+	cancel context.CancelFunc
+	done   chan error
+}
+
+// start the runnable of the singleton
+func (sg *singleton) start(ctx context.Context) *run {
+	var r = &run{done: make(chan error, 1)}
+	ctx, r.cancel = context.WithCancel(ctx)
+	go func() { r.done <- sg.runnable.Start(ctx) }()
+	return r
+}
+
+// Start implements manager.Runnable
+func (sg *singleton) Start(ctx context.Context) error {
+
+	// the shards also expire without a change, if the leases could not be renewed
+	var ticker = time.NewTicker(sg.sharder.RenewInterval)
+	defer ticker.Stop()
+
+	var running *run
+	var done chan error
+	var finished bool
+	defer func() {
+		if running != nil {
+			running.cancel()
+			<-running.done
+		}
+	}()
+
+	for {
+		var owns = sg.sharder.ownsShard(singletonShard)
+		switch {
+		case owns && running == nil && !finished:
+			running = sg.start(ctx)
+			done = running.done
+
+		case !owns && running != nil:
+			running.cancel()
+			if err := <-running.done; err != nil {
+				sg.sharder.Log.Error(err, "error stopping a singleton")
+			}
+			running, done = nil, nil
+
+		case !owns:
+			finished = false
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-done:
+			if err != nil {
+				return err
+			}
+			running.cancel()
+			running, done, finished = nil, nil, true
+		case <-sg.changes:
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, the replica of the
+// shard 0 runs the singleton instead of the leader
+func (sg *singleton) NeedLeaderElection() bool {
+	return false
+}
@@ -0,0 +1,154 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing traces the reconciliations of the global objects with
+// opentelemetry, the spans are exported to stdout or to an otlp collector,
+// without an exporter the spans are dropped by the noop tracer of otel
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// the exporters of the spans
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+// the name of the tracer and the service of the spans, the tracer is the
+// instrumentation scope and named after the go module by the conventions of
+// otel, the service is named after the operator, like its deployment
+const (
+	tracerName  = "github.com/jnnkrdb/configrdb"
+	serviceName = "confrdb"
+)
+
+// the attributes of the spans
+const (
+	// the kind, namespace and name of the global object
+	AttributeKind      = attribute.Key("confrdb.global.kind")
+	AttributeNamespace = attribute.Key("confrdb.global.namespace")
+	AttributeName      = attribute.Key("confrdb.global.name")
+
+	// the namespace, which is written to
+	AttributeTarget = attribute.Key("confrdb.target.namespace")
+
+	// the write in the target namespace, one of create, update, delete or patch
+	AttributeAction = attribute.Key("confrdb.action")
+
+	// the number of matching and avoided namespaces of a global object
+	AttributeMatched = attribute.Key("confrdb.namespaces.matched")
+	AttributeAvoided = attribute.Key("confrdb.namespaces.avoided")
+
+	// how the traced operation ended, one of success, requeue or error
+	AttributeOutcome = attribute.Key("confrdb.outcome")
+)
+
+// the outcomes of the traced operations
+const (
+	OutcomeSuccess = "success"
+	OutcomeRequeue = "requeue"
+	OutcomeError   = "error"
+)
+
+// the options of the exported spans
+type Options struct {
+	// where the spans are exported to, one of [ExporterNone], [ExporterStdout] or [ExporterOTLP]
+	Exporter string
+
+	// the address of the otlp collector
+	Endpoint string
+
+	// connect to the otlp collector without tls
+	Insecure bool
+
+	// the fraction of the traces, which are sampled
+	SampleRatio float64
+}
+
+// set up the global tracer provider, the returned function flushes the
+// remaining spans and stops the exporter
+func Setup(ctx context.Context, opts Options) (func(context.Context) error, error) {
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch opts.Exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New()
+	case ExporterOTLP:
+		var clientOpts = []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(opts.Endpoint)}
+		if opts.Insecure {
+			clientOpts = append(clientOpts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, clientOpts...)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q, must be one of %s, %s or %s", opts.Exporter, ExporterNone, ExporterStdout, ExporterOTLP)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error creating the %s exporter: %w", opts.Exporter, err)
+	}
+
+	var provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(serviceName))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
+
+// start a span as child of the span in the context
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// end a span with the outcome of the traced operation, an error is recorded on the span
+func End(span trace.Span, outcome string, err error) {
+	if err != nil {
+		outcome = OutcomeError
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.SetAttributes(AttributeOutcome.String(outcome))
+	span.End()
+}
+
+// get the attributes of a global object
+func Global(kind string, key types.NamespacedName) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		AttributeKind.String(kind),
+		AttributeNamespace.String(key.Namespace),
+		AttributeName.String(key.Name),
+	}
+}
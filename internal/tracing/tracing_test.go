@@ -0,0 +1,120 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestSetup(t *testing.T) {
+	for _, exporter := range []string{ExporterNone, ExporterStdout} {
+		shutdown, err := Setup(context.Background(), Options{Exporter: exporter, SampleRatio: 1})
+		if err != nil {
+			t.Fatalf("%s: %v", exporter, err)
+		}
+		if err = shutdown(context.Background()); err != nil {
+			t.Errorf("%s: %v", exporter, err)
+		}
+	}
+	if _, err := Setup(context.Background(), Options{Exporter: "jaeger"}); err == nil {
+		t.Error("expected an error for an unknown exporter")
+	}
+}
+
+func TestSetupOTLP(t *testing.T) {
+	// a collector, which only accepts the connection of the exporter
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	var connected = make(chan struct{})
+	go func() {
+		if conn, err := lis.Accept(); err == nil {
+			close(connected)
+			conn.Close()
+		}
+	}()
+
+	shutdown, err := Setup(context.Background(), Options{Exporter: ExporterOTLP, Endpoint: lis.Addr().String(), Insecure: true, SampleRatio: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := Start(context.Background(), "GlobalConfig.Reconcile")
+	End(span, OutcomeSuccess, nil)
+
+	// the shutdown flushes the span to the collector, the collector does not
+	// speak grpc, so the export itself fails
+	ctx, cancel := context.WithCancel(context.Background())
+	var done = make(chan struct{})
+	go func() {
+		_ = shutdown(ctx)
+		close(done)
+	}()
+	select {
+	case <-connected:
+	case <-time.After(5 * time.Second):
+		t.Error("expected the otlp exporter to connect to the collector")
+	}
+	cancel()
+	<-done
+
+	// the error of the exporter is returned
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err = Setup(ctx, Options{Exporter: ExporterOTLP, Endpoint: lis.Addr().String(), Insecure: true}); err == nil || !strings.Contains(err.Error(), "otlp exporter") {
+		t.Errorf("expected the error of the otlp exporter, got %v", err)
+	}
+}
+
+func TestEnd(t *testing.T) {
+	var recorder = tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	var global = Global("GlobalConfig", types.NamespacedName{Namespace: "default", Name: "gc"})
+	_, span := Start(context.Background(), "GlobalConfig.Reconcile", global...)
+	End(span, OutcomeRequeue, nil)
+	_, span = Start(context.Background(), "GlobalConfig.create", append(global, AttributeTarget.String("team-a"))...)
+	End(span, OutcomeSuccess, errors.New("forbidden"))
+
+	var outcomes []string
+	for _, s := range recorder.Ended() {
+		var attrs = attribute.NewSet(s.Attributes()...)
+		if name, _ := attrs.Value(AttributeName); name.AsString() != "gc" {
+			t.Errorf("%s: expected the name of the global object, got %q", s.Name(), name.AsString())
+		}
+		outcome, _ := attrs.Value(AttributeOutcome)
+		outcomes = append(outcomes, outcome.AsString())
+	}
+	if len(outcomes) != 2 || outcomes[0] != OutcomeRequeue || outcomes[1] != OutcomeError {
+		t.Errorf("expected the outcomes [requeue error], got %v", outcomes)
+	}
+	if events := recorder.Ended()[1].Events(); len(events) != 1 {
+		t.Errorf("expected the error to be recorded, got %d events", len(events))
+	}
+}
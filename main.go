@@ -17,22 +17,47 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	"golang.org/x/time/rate"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/workqueue"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/ratelimiter"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/conversion"
 
+	configv1alpha1 "github.com/jnnkrdb/configrdb/api/config/v1alpha1"
+	globalsv1 "github.com/jnnkrdb/configrdb/api/v1"
 	globalsv1beta2 "github.com/jnnkrdb/configrdb/api/v1beta2"
 	"github.com/jnnkrdb/configrdb/controllers"
+	"github.com/jnnkrdb/configrdb/internal/audit"
+	"github.com/jnnkrdb/configrdb/internal/health"
+	"github.com/jnnkrdb/configrdb/internal/keys"
+	"github.com/jnnkrdb/configrdb/internal/orphans"
+	"github.com/jnnkrdb/configrdb/internal/sharding"
+	"github.com/jnnkrdb/configrdb/internal/tracing"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -45,33 +70,146 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(globalsv1beta2.AddToScheme(scheme))
+	utilruntime.Must(globalsv1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
 func main() {
-	var metricsAddr string
-	var enableLeaderElection bool
-	var probeAddr string
-	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
-	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
-	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
+	var configFile string
+	var cfg = configv1alpha1.Defaults()
+	cfg.ProtectedNamespaces = append([]string{}, globalsv1beta2.DefaultProtectedNamespaces...)
+
+	flag.StringVar(&configFile, "config", "",
+		"The path of the configuration file of the kind "+configv1alpha1.Kind+". "+
+			"The flags, which are set explicitly, override the values of the file.")
+	flag.StringVar(&cfg.Metrics.BindAddress, "metrics-bind-address", cfg.Metrics.BindAddress, "The address the metric endpoint binds to.")
+	flag.StringVar(&cfg.Health.HealthProbeBindAddress, "health-probe-bind-address", cfg.Health.HealthProbeBindAddress, "The address the probe endpoint binds to.")
+	flag.BoolVar(&cfg.LeaderElection.LeaderElect, "leader-elect", cfg.LeaderElection.LeaderElect,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.Var(listFlag{&cfg.ProtectedNamespaces}, "protected-namespaces",
+		"Comma separated list of namespaces, which are never replicated into, unless a global object "+
+			"opts in with the annotation "+globalsv1beta2.AnnotationAllowProtectedNamespaces+". "+
+			"The namespace of the manager is always protected.")
+	flag.StringVar(&cfg.Orphans.Policy, "orphan-policy", cfg.Orphans.Policy,
+		"What happens with the replicated configmaps and secrets, whose global object does not exist anymore. "+
+			"One of delete, report or ignore.")
+	flag.DurationVar(&cfg.Orphans.Interval.Duration, "orphan-interval", cfg.Orphans.Interval.Duration,
+		"The interval, in which the orphans are searched, after the search at the start of the manager. "+
+			"0 only searches at the start.")
+	flag.Var(listFlag{&cfg.WatchNamespaces}, "watch-namespaces",
+		"Comma separated list of namespaces, whose GlobalConfigs and GlobalSecrets are reconciled. "+
+			"Empty reconciles the global objects of all namespaces.")
+	flag.Var(listFlag{&cfg.TargetNamespaces}, "target-namespaces",
+		"Comma separated list of namespaces, which are the only namespaces replicated into. "+
+			"Empty replicates into all namespaces. If both lists are set, the manager only caches these namespaces, "+
+			"the Namespaces and the GlobalReplicationPolicies are still watched cluster-wide, so the manager needs "+
+			"a ClusterRole to read them besides the Roles in these namespaces.")
+	flag.IntVar(&cfg.Sharding.Shards, "shards", cfg.Sharding.Shards,
+		"The number of shards, the global objects are split into. The replicas claim the shards with leases "+
+			"and each replica only reconciles the global objects of its shards. 0 disables the sharding. "+
+			"The sharding replaces the leader election.")
+	flag.DurationVar(&cfg.Sharding.LeaseDuration.Duration, "shard-lease-duration", cfg.Sharding.LeaseDuration.Duration,
+		"The duration, after which the shards of a replica, which did not renew its leases, are claimed by the other replicas.")
+	flag.DurationVar(&cfg.Sharding.RenewInterval.Duration, "shard-renew-interval", cfg.Sharding.RenewInterval.Duration,
+		"The interval, in which a replica renews the leases of its shards and rebalances the shards.")
+	flag.IntVar(&cfg.Controller.MaxConcurrentReconciles, "max-concurrent-reconciles", cfg.Controller.MaxConcurrentReconciles,
+		"The number of GlobalConfigs and the number of GlobalSecrets, which are reconciled at the same time.")
+	flag.DurationVar(&cfg.Controller.ResyncPeriod.Duration, "resync-period", cfg.Controller.ResyncPeriod.Duration,
+		"The interval, in which the global objects are reconciled again without an event, "+
+			"unless they set spec.resyncInterval. 0 disables the periodic reconciliation.")
+	flag.DurationVar(&cfg.Controller.RateLimiter.BaseDelay.Duration, "rate-limiter-base-delay", cfg.Controller.RateLimiter.BaseDelay.Duration,
+		"The delay of the first retry of a failed reconciliation, which doubles with every further failure.")
+	flag.DurationVar(&cfg.Controller.RateLimiter.MaxDelay.Duration, "rate-limiter-max-delay", cfg.Controller.RateLimiter.MaxDelay.Duration,
+		"The maximum delay of the retries of a failed reconciliation.")
+	flag.Float64Var(&cfg.Controller.RateLimiter.QPS, "rate-limiter-qps", cfg.Controller.RateLimiter.QPS,
+		"The overall number of reconciliations per second, which are started per kind.")
+	flag.IntVar(&cfg.Controller.RateLimiter.Burst, "rate-limiter-burst", cfg.Controller.RateLimiter.Burst,
+		"The number of reconciliations, which may exceed the rate-limiter-qps in a burst.")
+	flag.StringVar(&cfg.Controller.ContentHashKeySecret, "content-hash-key-secret", cfg.Controller.ContentHashKeySecret,
+		"The Secret in the namespace of the operator, whose key \"key\" keys the content hashes of the replicated objects. "+
+			"The Secret is created with a random key, if it does not exist.")
+	flag.StringVar(&cfg.Tracing.Exporter, "tracing-exporter", cfg.Tracing.Exporter,
+		"Where the spans of the reconciliations are exported to, one of none, stdout or otlp.")
+	flag.StringVar(&cfg.Tracing.Endpoint, "tracing-endpoint", cfg.Tracing.Endpoint,
+		"The address of the OTLP collector, which receives the spans over gRPC.")
+	flag.BoolVar(&cfg.Tracing.Insecure, "tracing-insecure", cfg.Tracing.Insecure,
+		"Connect to the OTLP collector without TLS.")
+	flag.Float64Var(&cfg.Tracing.SampleRatio, "tracing-sample-ratio", cfg.Tracing.SampleRatio,
+		"The fraction of the reconciliations, which are traced, between 0 and 1.")
+	flag.StringVar(&cfg.Audit.Path, "audit-log-path", cfg.Audit.Path,
+		"The file, which the audit records of the changes to the cluster are appended to, \"-\" writes them to stdout. "+
+			"Empty disables the file.")
+	flag.StringVar(&cfg.Audit.ConfigMap, "audit-configmap", cfg.Audit.ConfigMap,
+		"The ConfigMap in the namespace of the operator, which keeps the latest audit records. Empty disables the ConfigMap.")
+	flag.IntVar(&cfg.Audit.ConfigMapSize, "audit-configmap-size", cfg.Audit.ConfigMapSize,
+		"The number of audit records, which are kept in the ConfigMap.")
+	flag.DurationVar(&cfg.Audit.ConfigMapFlushInterval.Duration, "audit-configmap-flush-interval", cfg.Audit.ConfigMapFlushInterval.Duration,
+		"The interval, in which the buffered audit records are written to the ConfigMap.")
+	flag.StringVar(&cfg.Audit.KeySecret, "audit-key-secret", cfg.Audit.KeySecret,
+		"The Secret in the namespace of the operator, whose key \"key\" signs the audit records. Required, if the audit is enabled.")
+	flag.Var(featureGatesFlag{&cfg.FeatureGates}, "feature-gates",
+		"Comma separated list of features, which are switched on or off, e.g. "+configv1alpha1.FeatureWorkloadRollout+"=false. "+
+			"Known features: "+strings.Join(configv1alpha1.KnownFeatures(), ", ")+".")
+	// the production logging writes structured json, --zap-devel switches to the
+	// console encoder of the development, which also prints the whole objects
 	opts := zap.Options{
-		Development: true,
+		Development: false,
 	}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	if configFile != "" {
+		if err := configv1alpha1.Load(configFile, cfg); err != nil {
+			setupLog.Error(err, "unable to load the configuration file")
+			os.Exit(1)
+		}
+		// the flags, which are set explicitly, override the file
+		if err := flag.CommandLine.Parse(os.Args[1:]); err != nil {
+			setupLog.Error(err, "unable to parse the flags")
+			os.Exit(1)
+		}
+	}
+	if errs := cfg.Validate(); len(errs) > 0 {
+		setupLog.Error(errs.ToAggregate(), "invalid configuration")
+		os.Exit(1)
+	}
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+		SampleRatio: cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		setupLog.Error(err, "unable to set up the tracing")
+		os.Exit(1)
+	}
+
+	var protected = append([]string{}, cfg.ProtectedNamespaces...)
+	if ns := operatorNamespace(); ns != "" {
+		protected = append(protected, ns)
+	}
+	setupLog.Info("protecting namespaces", "namespaces", protected)
+
+	var watched, targets = cfg.WatchNamespaces, cfg.TargetNamespaces
+	var newCache cache.NewCacheFunc
+	if cached := cacheNamespaces(watched, targets); len(cached) > 0 {
+		setupLog.Info("restricting the cache", "namespaces", cached)
+		newCache = cache.MultiNamespacedCacheBuilder(cached)
+	}
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+		NewCache:               newCache,
 		Scheme:                 scheme,
-		MetricsBindAddress:     metricsAddr,
-		Port:                   9443,
-		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "80de5eb2.jnnkrdb.de",
+		MetricsBindAddress:     cfg.Metrics.BindAddress,
+		Port:                   cfg.Webhook.Port,
+		CertDir:                cfg.Webhook.CertDir,
+		HealthProbeBindAddress: cfg.Health.HealthProbeBindAddress,
+		LeaderElection:         cfg.LeaderElection.LeaderElect,
+		LeaderElectionID:       cfg.LeaderElection.ResourceName,
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -89,34 +227,375 @@ func main() {
 		os.Exit(1)
 	}
 
+	var sharder *sharding.Sharder
+	if cfg.Sharding.Shards > 0 {
+		var namespace = operatorNamespace()
+		if namespace == "" {
+			setupLog.Error(nil, "the sharding requires the namespace of the operator, set the environment variable POD_NAMESPACE")
+			os.Exit(1)
+		}
+		sharder = &sharding.Sharder{
+			Reader:        mgr.GetAPIReader(),
+			Client:        mgr.GetClient(),
+			Namespace:     namespace,
+			Identity:      replicaIdentity(),
+			Shards:        cfg.Sharding.Shards,
+			LeaseDuration: cfg.Sharding.LeaseDuration.Duration,
+			RenewInterval: cfg.Sharding.RenewInterval.Duration,
+			Log:           ctrl.Log.WithName("sharding"),
+		}
+		if err = mgr.Add(sharder); err != nil {
+			setupLog.Error(err, "unable to add the sharder")
+			os.Exit(1)
+		}
+	}
+
+	auditor, err := newAuditor(cfg.Audit, mgr)
+	if err != nil {
+		setupLog.Error(err, "unable to set up the audit")
+		os.Exit(1)
+	}
+
+	// the content hashes are annotated on the replicated objects and the pod templates, so
+	// they are keyed, the replicas share the key through a secret
+	var namespace = operatorNamespace()
+	if namespace == "" {
+		setupLog.Error(nil, "the key of the content hashes requires the namespace of the operator, set the environment variable POD_NAMESPACE")
+		os.Exit(1)
+	}
+	hashKey, err := keys.Ensure(context.Background(), mgr.GetAPIReader(), mgr.GetClient(), namespace, cfg.Controller.ContentHashKeySecret)
+	if err != nil {
+		setupLog.Error(err, "unable to get the key of the content hashes")
+		os.Exit(1)
+	}
+
+	var controllerOptions = controller.Options{
+		MaxConcurrentReconciles: cfg.Controller.MaxConcurrentReconciles,
+		RateLimiter:             rateLimiter(cfg.Controller.RateLimiter),
+	}
+
 	if err = (&controllers.GlobalConfigReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:                 mgr.GetClient(),
+		Scheme:                 mgr.GetScheme(),
+		ProtectedNamespaces:    protected,
+		WatchNamespaces:        watched,
+		TargetNamespaces:       targets,
+		Sharder:                sharder,
+		Audit:                  auditor,
+		HashKey:                hashKey,
+		ResyncPeriod:           cfg.Controller.ResyncPeriod.Duration,
+		DisableWorkloadRollout: !cfg.Enabled(configv1alpha1.FeatureWorkloadRollout),
+		Options:                controllerOptions,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "GlobalConfig")
 		os.Exit(1)
 	}
 	if err = (&controllers.GlobalSecretReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:                 mgr.GetClient(),
+		Scheme:                 mgr.GetScheme(),
+		ProtectedNamespaces:    protected,
+		WatchNamespaces:        watched,
+		TargetNamespaces:       targets,
+		Sharder:                sharder,
+		Audit:                  auditor,
+		HashKey:                hashKey,
+		ResyncPeriod:           cfg.Controller.ResyncPeriod.Duration,
+		DisableWorkloadRollout: !cfg.Enabled(configv1alpha1.FeatureWorkloadRollout),
+		Options:                controllerOptions,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "GlobalSecret")
 		os.Exit(1)
 	}
+	policy, err := orphans.ParsePolicy(cfg.Orphans.Policy)
+	if err != nil {
+		setupLog.Error(err, "invalid orphan policy")
+		os.Exit(1)
+	}
+	var collector manager.Runnable = &orphans.Collector{
+		Reader:   mgr.GetAPIReader(),
+		Client:   mgr.GetClient(),
+		Scope:    orphans.Scope{WatchNamespaces: watched, TargetNamespaces: targets},
+		Audit:    auditor,
+		Policy:   policy,
+		Interval: cfg.Orphans.Interval.Duration,
+		Log:      ctrl.Log.WithName("orphans"),
+	}
+	// the sharding replaces the leader election, so the replica of the shard 0 collects the orphans
+	if sharder != nil {
+		collector = sharder.Singleton(collector)
+	}
+	if err = mgr.Add(collector); err != nil {
+		setupLog.Error(err, "unable to add the orphan collector")
+		os.Exit(1)
+	}
+
+	// the webhook server needs its certificate, a missing certificate is reported before
+	// the start, instead of failing the manager later on
+	var serveWebhooks = cfg.Enabled(configv1alpha1.FeatureWebhooks) && os.Getenv("ENABLE_WEBHOOKS") != "false"
+	var serveConversion = cfg.Enabled(configv1alpha1.FeatureConversionWebhook)
+	if serveWebhooks || serveConversion {
+		if err = webhookCertificate(cfg.Webhook.CertDir); err != nil {
+			setupLog.Error(err, "the webhook server requires a serving certificate, e.g. of cert-manager, "+
+				"without it, switch off the feature gates "+configv1alpha1.FeatureWebhooks+" and "+configv1alpha1.FeatureConversionWebhook)
+			os.Exit(1)
+		}
+	}
+
+	// v1 is the storage version, so the stored objects can only be read as v1beta2 through
+	// the conversion webhook, ENABLE_WEBHOOKS only switches off the defaulting and validating webhooks
+	if serveConversion {
+		mgr.GetWebhookServer().Register("/convert", &conversion.Webhook{})
+	}
+
+	if serveWebhooks {
+		var validator = &globalsv1beta2.GlobalValidator{
+			Client:              mgr.GetClient(),
+			ProtectedNamespaces: protected,
+		}
+		if err = (&globalsv1beta2.GlobalConfig{}).SetupWebhookWithManager(mgr, validator); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "GlobalConfig")
+			os.Exit(1)
+		}
+		if err = (&globalsv1beta2.GlobalSecret{}).SetupWebhookWithManager(mgr, validator); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "GlobalSecret")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
 	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+	// the namespaces are only listed by the reconcilers, so their informer is started
+	// with the manager, instead of during the first reconciliation, only these informers
+	// gate the readiness, the global objects need the conversion webhook of this pod
+	var informers []health.Informer
+	for _, obj := range []client.Object{&v1.Namespace{}, &v1.ConfigMap{}, &v1.Secret{}} {
+		informer, err := mgr.GetCache().GetInformer(context.Background(), obj)
+		if err != nil {
+			setupLog.Error(err, "unable to set up the informer", "kind", fmt.Sprintf("%T", obj))
+			os.Exit(1)
+		}
+		informers = append(informers, informer)
+	}
+	if err := mgr.AddReadyzCheck("informers", health.InformersSynced(informers...)); err != nil {
 		setupLog.Error(err, "unable to set up ready check")
 		os.Exit(1)
 	}
+	if serveWebhooks || serveConversion {
+		if err := mgr.AddReadyzCheck("webhook", mgr.GetWebhookServer().StartedChecker()); err != nil {
+			setupLog.Error(err, "unable to set up ready check")
+			os.Exit(1)
+		}
+	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	err = mgr.Start(ctrl.SetupSignalHandler())
+
+	// the remaining spans are flushed, before the binary ends
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if sErr := shutdownTracing(ctx); sErr != nil {
+		setupLog.Error(sErr, "unable to flush the spans")
+	}
+	cancel()
+
+	if err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
+
+// check, that the certificate and the key of the webhook server exist, an empty
+// directory is the default directory of controller-runtime
+func webhookCertificate(dir string) error {
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "k8s-webhook-server", "serving-certs")
+	}
+	for _, name := range []string{"tls.crt", "tls.key"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			return fmt.Errorf("missing the certificate of the webhook server: %w", err)
+		}
+	}
+	return nil
+}
+
+// get the namespace, the manager is running in, from the environment variable
+// POD_NAMESPACE or the mounted serviceaccount
+func operatorNamespace() string {
+	if ns := os.Getenv("POD_NAMESPACE"); ns != "" {
+		return ns
+	}
+	if ns, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace"); err == nil {
+		return strings.TrimSpace(string(ns))
+	}
+	return ""
+}
+
+// get the unique name of the replica from the environment variable POD_NAME
+// or the hostname, which is the name of the pod by default
+func replicaIdentity() string {
+	if name := os.Getenv("POD_NAME"); name != "" {
+		return name
+	}
+	name, _ := os.Hostname()
+	return name
+}
+
+// get the auditor of the changes to the cluster, nil if the audit is disabled
+//
+// the records of a replica form a chain, which is resumed from the last record
+// of the replica in the file or the configmap, e.g. after a restart of the pod,
+// the chain is signed with the key of the secret in the namespace of the operator
+func newAuditor(cfg configv1alpha1.AuditConfig, mgr ctrl.Manager) (*audit.Auditor, error) {
+	if cfg.Path == "" && cfg.ConfigMap == "" {
+		return nil, nil
+	}
+
+	var namespace = operatorNamespace()
+	if namespace == "" {
+		return nil, errors.New("the audit requires the namespace of the operator, set the environment variable POD_NAMESPACE")
+	}
+	key, err := audit.KeyFromSecret(context.Background(), mgr.GetAPIReader(), namespace, cfg.KeySecret)
+	if err != nil {
+		return nil, err
+	}
+
+	var identity = replicaIdentity()
+	var sinks []audit.Sink
+	var last *audit.Record
+
+	switch cfg.Path {
+	case "":
+	case "-":
+		sinks = append(sinks, audit.NewWriterSink(os.Stdout))
+	default:
+		sink, rec, err := audit.OpenFile(cfg.Path, identity)
+		if err != nil {
+			return nil, err
+		}
+		sinks, last = append(sinks, sink), rec
+	}
+
+	if cfg.ConfigMap != "" {
+		var sink = &audit.ConfigMapSink{
+			Reader:        mgr.GetAPIReader(),
+			Client:        mgr.GetClient(),
+			Namespace:     namespace,
+			Name:          cfg.ConfigMap,
+			Size:          cfg.ConfigMapSize,
+			FlushInterval: cfg.ConfigMapFlushInterval.Duration,
+			Log:           ctrl.Log.WithName("audit"),
+		}
+		rec, err := sink.Last(context.Background(), identity)
+		if err != nil {
+			return nil, err
+		}
+		if rec != nil && (last == nil || rec.Sequence > last.Sequence) {
+			last = rec
+		}
+		// the records are written in batches, see ConfigMapSink
+		if err = mgr.Add(sink); err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	var auditor = audit.New(identity, key, sinks...)
+	auditor.Resume(last)
+	return auditor, nil
+}
+
+// get the rate limiter of the controllers, the failed reconciliations of a global
+// object are retried with an exponential backoff and all reconciliations of a kind
+// are limited by a token bucket, like the default rate limiter of the controllers
+func rateLimiter(cfg configv1alpha1.RateLimiterConfig) ratelimiter.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(cfg.BaseDelay.Duration, cfg.MaxDelay.Duration),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(cfg.QPS), cfg.Burst)},
+	)
+}
+
+// split a comma separated list and drop the empty items
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// get the namespaces, which are cached by the manager
+//
+// the global objects are read from the watched namespaces and the replicated objects
+// from the target namespaces, so both lists must be set to restrict the cache,
+// otherwise the whole cluster is cached
+func cacheNamespaces(watched, targets []string) []string {
+	if len(watched) == 0 || len(targets) == 0 {
+		return nil
+	}
+	var seen = make(map[string]bool, len(watched)+len(targets))
+	var namespaces []string
+	for _, ns := range append(append([]string{}, watched...), targets...) {
+		if !seen[ns] {
+			seen[ns] = true
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+// a flag of a comma separated list, which replaces the list
+type listFlag struct {
+	list *[]string
+}
+
+func (f listFlag) String() string {
+	if f.list == nil {
+		return ""
+	}
+	return strings.Join(*f.list, ",")
+}
+
+func (f listFlag) Set(value string) error {
+	*f.list = splitList(value)
+	return nil
+}
+
+// a flag of comma separated feature gates like "Feature=true,Other=false",
+// which are added to the feature gates
+type featureGatesFlag struct {
+	gates *map[string]bool
+}
+
+func (f featureGatesFlag) String() string {
+	if f.gates == nil {
+		return ""
+	}
+	var items = make([]string, 0, len(*f.gates))
+	for feature, enabled := range *f.gates {
+		items = append(items, feature+"="+strconv.FormatBool(enabled))
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
+
+func (f featureGatesFlag) Set(value string) error {
+	if *f.gates == nil {
+		*f.gates = make(map[string]bool)
+	}
+	for _, item := range splitList(value) {
+		feature, enabled, ok := strings.Cut(item, "=")
+		if !ok {
+			return fmt.Errorf("invalid feature gate %q, expected Feature=true or Feature=false", item)
+		}
+		b, err := strconv.ParseBool(strings.TrimSpace(enabled))
+		if err != nil {
+			return fmt.Errorf("invalid feature gate %q: %w", item, err)
+		}
+		(*f.gates)[strings.TrimSpace(feature)] = b
+	}
+	return nil
+}